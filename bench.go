@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	benchVersionsArg []string
+	benchSaveArg     string
+	benchFilesArg    bool
+)
+
+var BenchCmd = &cobra.Command{
+	Use:   "bench --versions 1.21,1.22 -- [packages] [go test flags]",
+	Short: "Compare benchmarks across versions",
+	Long: `Compare benchmarks across versions.
+"go test -bench" is run with each version one by one, and the results are
+compared with the first version like benchstat. -run '^$' is added unless
+-run is given. With --files, the args are result files saved by --save.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		var err error
+		if benchFilesArg {
+			err = CompareBenchFiles(ctx, args)
+		} else {
+			err = Bench(ctx, benchVersionsArg, args)
+		}
+		exitOnError(ctx, err)
+	},
+}
+
+func init() {
+	BenchCmd.Flags().StringSliceVar(&benchVersionsArg, "versions", nil, "versions to compare (comma separated). the first one is the base")
+	BenchCmd.Flags().StringVar(&benchSaveArg, "save", "", "directory to save the raw results as <version>.txt")
+	BenchCmd.Flags().BoolVar(&benchFilesArg, "files", false, "compare saved result files instead of running benchmarks")
+	BenchCmd.Flags().SetInterspersed(false)
+}
+
+func Bench(ctx context.Context, versions []string, args []string) error {
+	baseDir, err := checkInit()
+	if err != nil {
+		return err
+	}
+	if len(versions) < 2 {
+		return fmt.Errorf("specify at least two versions")
+	}
+	versionDirs, err := matrixVersionDirs(ctx, baseDir, versions)
+	if err != nil {
+		return err
+	}
+
+	testArgs := benchTestArgs(args)
+
+	var (
+		labels []string
+		sets   []*benchSet
+	)
+	for _, versionDir := range versionDirs {
+		name := filepath.Base(versionDir)
+		infof(ctx, "run benchmarks with %s", name)
+
+		var out bytes.Buffer
+		cmd, err := versionCommand(ctx, baseDir, versionDir, "go", testArgs)
+		if err != nil {
+			return err
+		}
+		cmd.Env = setEnviron(cmd.Env, "GOTOOLCHAIN", "local")
+		cmd.Stdout = io.MultiWriter(&out, os.Stderr)
+		cmd.Stderr = os.Stderr
+		if err := startAndWait(cmd); err != nil {
+			return err
+		}
+		if benchSaveArg != "" {
+			if err := os.MkdirAll(benchSaveArg, 0o755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(filepath.Join(benchSaveArg, name+".txt"), out.Bytes(), 0o644); err != nil {
+				return err
+			}
+		}
+		set, err := parseBench(&out)
+		if err != nil {
+			return err
+		}
+		labels = append(labels, name)
+		sets = append(sets, set)
+	}
+	return writeBenchTable(os.Stdout, labels, sets)
+}
+
+// benchTestArgs returns the args of go test for the args of gvs bench.
+// -run and -bench are added before the packages unless they are given, and
+// the flags after -args are for the test binary.
+func benchTestArgs(args []string) []string {
+	goArgs := args
+	if i := slices.Index(args, "-args"); i >= 0 {
+		goArgs = args[:i]
+	}
+	testArgs := []string{"test"}
+	if !slices.ContainsFunc(goArgs, func(arg string) bool {
+		return arg == "-run" || strings.HasPrefix(arg, "-run=")
+	}) {
+		testArgs = append(testArgs, "-run=^$")
+	}
+	if !slices.ContainsFunc(goArgs, func(arg string) bool {
+		return arg == "-bench" || strings.HasPrefix(arg, "-bench=")
+	}) {
+		testArgs = append(testArgs, "-bench=.")
+	}
+	return append(testArgs, args...)
+}
+
+// CompareBenchFiles compares the results saved by Bench or go test -bench.
+func CompareBenchFiles(_ context.Context, files []string) error {
+	if len(files) < 2 {
+		return fmt.Errorf("specify at least two files")
+	}
+	var (
+		labels []string
+		sets   []*benchSet
+	)
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		set, err := parseBench(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", file, err)
+		}
+		labels = append(labels, strings.TrimSuffix(filepath.Base(file), ".txt"))
+		sets = append(sets, set)
+	}
+	return writeBenchTable(os.Stdout, labels, sets)
+}
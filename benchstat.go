@@ -0,0 +1,407 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// benchSet is the parsed output of go test -bench. Values are keyed by
+// benchmark and unit.
+type benchSet struct {
+	names  []string
+	units  []string
+	values map[string]map[string][]float64
+}
+
+func parseBench(r io.Reader) (*benchSet, error) {
+	set := &benchSet{values: make(map[string]map[string][]float64)}
+	var pkgs []string
+	var pkg string
+
+	type sample struct {
+		pkg, name, unit string
+		value           float64
+	}
+	var samples []sample
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if p, ok := strings.CutPrefix(line, "pkg: "); ok {
+			pkg = strings.TrimSpace(p)
+			if !slices.Contains(pkgs, pkg) {
+				pkgs = append(pkgs, pkg)
+			}
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") || len(fields)%2 != 0 {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			continue
+		}
+		for i := 2; i+1 < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				break
+			}
+			samples = append(samples, sample{pkg: pkg, name: strings.TrimPrefix(fields[0], "Benchmark"), unit: fields[i+1], value: value})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, s := range samples {
+		name := s.name
+		if len(pkgs) > 1 {
+			name = s.pkg[strings.LastIndex(s.pkg, "/")+1:] + "/" + name
+		}
+		if _, ok := set.values[name]; !ok {
+			set.names = append(set.names, name)
+			set.values[name] = make(map[string][]float64)
+		}
+		if !slices.Contains(set.units, s.unit) {
+			set.units = append(set.units, s.unit)
+		}
+		set.values[name][s.unit] = append(set.values[name][s.unit], s.value)
+	}
+	return set, nil
+}
+
+// benchSummary is the median of samples and its confidence interval.
+type benchSummary struct {
+	center   float64
+	lo, hi   float64
+	hasRange bool
+	samples  []float64
+}
+
+const benchConfidence = 0.95
+
+func summarize(values []float64) benchSummary {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	s := benchSummary{center: median(sorted), samples: sorted}
+	s.lo, s.hi, s.hasRange = medianCI(sorted, benchConfidence)
+	return s
+}
+
+func median(sorted []float64) float64 {
+	n := len(sorted)
+	if n == 0 {
+		return math.NaN()
+	}
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// medianCI returns the distribution-free confidence interval of the median
+// given by order statistics.
+func medianCI(sorted []float64, confidence float64) (lo, hi float64, ok bool) {
+	n := len(sorted)
+	k := 0
+	for j := 1; j <= n/2; j++ {
+		if 1-2*binomCDF(j-1, n) >= confidence {
+			k = j
+		}
+	}
+	if k == 0 {
+		return 0, 0, false
+	}
+	return sorted[k-1], sorted[n-k], true
+}
+
+// binomCDF returns P(X <= k) for X ~ Binomial(n, 0.5).
+func binomCDF(k, n int) float64 {
+	var p float64
+	for i := 0; i <= k; i++ {
+		lg := lgamma(n+1) - lgamma(i+1) - lgamma(n-i+1)
+		p += math.Exp(lg - float64(n)*math.Ln2)
+	}
+	return p
+}
+
+func lgamma(n int) float64 {
+	v, _ := math.Lgamma(float64(n))
+	return v
+}
+
+// mannWhitneyP returns the two-sided p-value of the Mann-Whitney U test like
+// benchstat. It is exact for up to 50 samples each, or 25 if there are
+// ties, and given by the normal approximation with tie correction otherwise.
+func mannWhitneyP(x, y []float64) float64 {
+	n1, n2 := len(x), len(y)
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+	type obs struct {
+		value float64
+		first bool
+	}
+	all := make([]obs, 0, n1+n2)
+	for _, v := range x {
+		all = append(all, obs{v, true})
+	}
+	for _, v := range y {
+		all = append(all, obs{v, false})
+	}
+	slices.SortFunc(all, func(l, r obs) int {
+		switch {
+		case l.value < r.value:
+			return -1
+		case l.value > r.value:
+			return 1
+		}
+		return 0
+	})
+
+	// ranks are doubled to keep the mid-ranks of ties integers.
+	var groups []int
+	var r1, ties float64
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].value == all[i].value {
+			j++
+		}
+		for _, o := range all[i:j] {
+			if o.first {
+				r1 += float64(i + j + 1)
+			}
+		}
+		t := float64(j - i)
+		ties += t*t*t - t
+		groups = append(groups, j-i)
+		i = j
+	}
+	if len(groups) == 1 {
+		return 1
+	}
+	exact := n1 <= 50 && n2 <= 50
+	if ties > 0 {
+		exact = n1 <= 25 && n2 <= 25
+	}
+	if exact {
+		return exactRankSumP(groups, n1, int(r1))
+	}
+
+	n := float64(n1 + n2)
+	u := r1/2 - float64(n1*(n1+1))/2
+	mu := float64(n1*n2) / 2
+	sigma := math.Sqrt(float64(n1*n2) / 12 * ((n + 1) - ties/(n*(n-1))))
+	z := (math.Abs(u-mu) - 0.5) / sigma
+	if z < 0 {
+		z = 0
+	}
+	return math.Erfc(z / math.Sqrt2)
+}
+
+// exactRankSumP returns the two-sided p-value of the doubled rank sum r1 of
+// n1 samples, given the sizes of the groups of tied values in order. The
+// distribution is counted over every choice of n1 of the values.
+func exactRankSumP(groups []int, n1, r1 int) float64 {
+	n := 0
+	for _, t := range groups {
+		n += t
+	}
+	// counts[k][s] is the number of choices of k values with the doubled
+	// rank sum s.
+	counts := make([][]float64, n1+1)
+	for k := range counts {
+		counts[k] = make([]float64, 2*n*n1+1)
+	}
+	counts[0][0] = 1
+	start := 0
+	for _, t := range groups {
+		rank := 2*start + t + 1
+		for k := n1; k >= 0; k-- {
+			for s := len(counts[k]) - 1; s >= 0; s-- {
+				if counts[k][s] == 0 {
+					continue
+				}
+				for c := 1; c <= t && k+c <= n1; c++ {
+					counts[k+c][s+c*rank] += counts[k][s] * binomial(t, c)
+				}
+			}
+		}
+		start += t
+	}
+
+	var total, lower, upper float64
+	for s, count := range counts[n1] {
+		total += count
+		if s <= r1 {
+			lower += count
+		}
+		if s >= r1 {
+			upper += count
+		}
+	}
+	return math.Min(1, 2*math.Min(lower, upper)/total)
+}
+
+func binomial(n, k int) float64 {
+	v := 1.0
+	for i := 1; i <= k; i++ {
+		v = v * float64(n-k+i) / float64(i)
+	}
+	return v
+}
+
+const benchAlpha = 0.05
+
+// writeBenchTable writes a comparison table per unit of the sets against the
+// first set, like benchstat.
+func writeBenchTable(w io.Writer, labels []string, sets []*benchSet) error {
+	var units, names []string
+	for _, set := range sets {
+		for _, unit := range set.units {
+			if !slices.Contains(units, unit) {
+				units = append(units, unit)
+			}
+		}
+		for _, name := range set.names {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, unit := range units {
+		displayUnit, scale := benchUnit(unit)
+
+		fmt.Fprintf(tw, "\t%s\t", labels[0])
+		for _, label := range labels[1:] {
+			fmt.Fprintf(tw, "%s\t\t", label)
+		}
+		fmt.Fprintf(tw, "\n%s\t%s\t", "", displayUnit)
+		for range labels[1:] {
+			fmt.Fprintf(tw, "%s\tvs base\t", displayUnit)
+		}
+		fmt.Fprintln(tw)
+
+		geo := make([][]float64, len(sets))
+		for _, name := range names {
+			summaries := make([]*benchSummary, len(sets))
+			complete := true
+			for i, set := range sets {
+				if values := set.values[name][unit]; len(values) > 0 {
+					s := summarize(values)
+					summaries[i] = &s
+				} else {
+					complete = false
+				}
+			}
+			if summaries[0] == nil {
+				continue
+			}
+
+			fmt.Fprintf(tw, "%s\t%s\t", name, formatSummary(*summaries[0], scale))
+			for _, s := range summaries[1:] {
+				if s == nil {
+					fmt.Fprint(tw, "\t\t")
+					continue
+				}
+				fmt.Fprintf(tw, "%s\t%s\t", formatSummary(*s, scale), formatDelta(*summaries[0], *s))
+			}
+			fmt.Fprintln(tw)
+
+			if complete {
+				for i, s := range summaries {
+					geo[i] = append(geo[i], s.center)
+				}
+			}
+		}
+
+		if len(geo[0]) > 1 {
+			base := geomean(geo[0])
+			fmt.Fprintf(tw, "geomean\t%s\t", formatValue(base*scale))
+			for _, g := range geo[1:] {
+				mean := geomean(g)
+				fmt.Fprintf(tw, "%s\t%+.2f%%\t", formatValue(mean*scale), (mean/base-1)*100)
+			}
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+func benchUnit(unit string) (string, float64) {
+	switch unit {
+	case "ns/op":
+		return "sec/op", 1e-9
+	case "MB/s":
+		return "B/s", 1e6
+	default:
+		return unit, 1
+	}
+}
+
+func formatSummary(s benchSummary, scale float64) string {
+	if s.hasRange && s.lo == 0 && s.hi == 0 {
+		return formatValue(0) + " ± 0%"
+	}
+	if !s.hasRange || s.center == 0 {
+		return formatValue(s.center*scale) + " ± ∞"
+	}
+	spread := math.Max(s.center-s.lo, s.hi-s.center) / s.center * 100
+	return fmt.Sprintf("%s ± %.0f%%", formatValue(s.center*scale), spread)
+}
+
+func formatDelta(base, s benchSummary) string {
+	p := mannWhitneyP(base.samples, s.samples)
+	n := fmt.Sprintf("(p=%.3f n=%d)", p, len(base.samples))
+	if len(base.samples) != len(s.samples) {
+		n = fmt.Sprintf("(p=%.3f n=%d+%d)", p, len(base.samples), len(s.samples))
+	}
+	if p >= benchAlpha || base.center == 0 {
+		return "~ " + n
+	}
+	return fmt.Sprintf("%+.2f%% %s", (s.center/base.center-1)*100, n)
+}
+
+func formatValue(v float64) string {
+	prefixes := []struct {
+		scale  float64
+		prefix string
+	}{
+		{1e12, "T"}, {1e9, "G"}, {1e6, "M"}, {1e3, "k"}, {1, ""}, {1e-3, "m"}, {1e-6, "µ"}, {1e-9, "n"},
+	}
+	abs := math.Abs(v)
+	if abs == 0 {
+		return "0.000"
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return strconv.FormatFloat(v, 'g', 4, 64)
+	}
+	for _, p := range prefixes {
+		if abs >= p.scale {
+			// four significant digits.
+			digits := int(math.Floor(math.Log10(abs/p.scale))) + 1
+			return strconv.FormatFloat(v/p.scale, 'f', max(0, 4-digits), 64) + p.prefix
+		}
+	}
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
+
+func geomean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		if v <= 0 {
+			return math.NaN()
+		}
+		sum += math.Log(v)
+	}
+	return math.Exp(sum / float64(len(values)))
+}
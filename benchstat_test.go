@@ -0,0 +1,313 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseBench(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		names  []string
+		units  []string
+		values map[string]map[string][]float64
+	}{
+		{
+			name: "one package",
+			input: `goos: linux
+goarch: amd64
+pkg: example.com/p
+cpu: Intel(R) Xeon(R)
+BenchmarkEncode-8   	  694311	      1718 ns/op	     320 B/op	       2 allocs/op
+BenchmarkEncode-8   	  700000	      1720 ns/op	     320 B/op	       2 allocs/op
+BenchmarkDecode/size=1k-8	  100	  12.5 ns/op	  81.92 MB/s
+PASS
+ok  	example.com/p	3.012s
+`,
+			names: []string{"Encode-8", "Decode/size=1k-8"},
+			units: []string{"ns/op", "B/op", "allocs/op", "MB/s"},
+			values: map[string]map[string][]float64{
+				"Encode-8":         {"ns/op": {1718, 1720}, "B/op": {320, 320}, "allocs/op": {2, 2}},
+				"Decode/size=1k-8": {"ns/op": {12.5}, "MB/s": {81.92}},
+			},
+		},
+		{
+			name: "packages prefix the names",
+			input: `pkg: example.com/p/a
+BenchmarkX 10 100 ns/op
+pkg: example.com/p/b
+BenchmarkX 10 200 ns/op
+`,
+			names: []string{"a/X", "b/X"},
+			units: []string{"ns/op"},
+			values: map[string]map[string][]float64{
+				"a/X": {"ns/op": {100}},
+				"b/X": {"ns/op": {200}},
+			},
+		},
+		{
+			name: "malformed lines are ignored",
+			input: `BenchmarkX
+BenchmarkX 10
+BenchmarkX many 100 ns/op
+BenchmarkX 10 100 ns/op extra
+--- FAIL: BenchmarkY
+BenchmarkX 10 100 ns/op
+`,
+			names:  []string{"X"},
+			units:  []string{"ns/op"},
+			values: map[string]map[string][]float64{"X": {"ns/op": {100}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := parseBench(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(set.names, tt.names) || !slices.Equal(set.units, tt.units) {
+				t.Fatalf("names, units = %q, %q, want %q, %q", set.names, set.units, tt.names, tt.units)
+			}
+			for name, units := range tt.values {
+				for unit, want := range units {
+					if got := set.values[name][unit]; !slices.Equal(got, want) {
+						t.Errorf("%s %s = %v, want %v", name, unit, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMannWhitneyP(t *testing.T) {
+	tests := []struct {
+		name string
+		x, y []float64
+		want float64
+	}{
+		// 2/C(6,3), 2/C(8,4), 2/C(10,5) and 2/C(20,10) are reported by
+		// benchstat as p=0.100, 0.029, 0.008 and 0.000.
+		{name: "3+3 separated", x: seq(1, 3), y: seq(4, 3), want: 2.0 / 20},
+		{name: "4+4 separated", x: seq(1, 4), y: seq(5, 4), want: 2.0 / 70},
+		{name: "5+5 separated", x: seq(1, 5), y: seq(6, 5), want: 2.0 / 252},
+		{name: "10+10 separated", x: seq(11, 10), y: seq(1, 10), want: 2.0 / 184756},
+		{name: "interleaved", x: []float64{1, 3, 5, 7}, y: []float64{2, 4, 6, 8}, want: 48.0 / 70},
+		{name: "identical", x: []float64{5, 5, 5}, y: []float64{5, 5, 5}, want: 1},
+		// ranks of x are 1, 2, 3.5 and 5.5, and 5 of C(8,4) choices have a
+		// rank sum of at most 12.
+		{name: "ties", x: []float64{1, 2, 3, 4}, y: []float64{3, 4, 5, 6}, want: 2 * 5.0 / 70},
+		{name: "empty", x: nil, y: seq(1, 3), want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mannWhitneyP(tt.x, tt.y); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("mannWhitneyP() = %v, want %v", got, tt.want)
+			}
+			if got := mannWhitneyP(tt.y, tt.x); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("mannWhitneyP() swapped = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMannWhitneyPMatchesPermutations(t *testing.T) {
+	x := []float64{3, 1, 4, 1, 5}
+	y := []float64{9, 2, 6, 5, 3, 5}
+	all := append(slices.Clone(x), y...)
+	rankSum := func(values []float64, mask int) (sum float64) {
+		for i := range values {
+			if mask&(1<<i) == 0 {
+				continue
+			}
+			var less, equal float64
+			for _, v := range values {
+				if v < values[i] {
+					less++
+				} else if v == values[i] {
+					equal++
+				}
+			}
+			sum += less + (equal+1)/2
+		}
+		return sum
+	}
+	observed := rankSum(all, 1<<len(x)-1)
+	var lower, upper, total float64
+	for mask := 0; mask < 1<<len(all); mask++ {
+		if popcount(mask) != len(x) {
+			continue
+		}
+		sum := rankSum(all, mask)
+		total++
+		if sum <= observed {
+			lower++
+		}
+		if sum >= observed {
+			upper++
+		}
+	}
+	want := math.Min(1, 2*math.Min(lower, upper)/total)
+	if got := mannWhitneyP(x, y); math.Abs(got-want) > 1e-9 {
+		t.Errorf("mannWhitneyP() = %v, want %v by permutations", got, want)
+	}
+}
+
+func TestMedianCI(t *testing.T) {
+	tests := []struct {
+		n      int
+		lo, hi float64
+		ok     bool
+	}{
+		{n: 5},
+		{n: 6, lo: 1, hi: 6, ok: true},
+		{n: 10, lo: 2, hi: 9, ok: true},
+		{n: 20, lo: 6, hi: 15, ok: true},
+	}
+	for _, tt := range tests {
+		lo, hi, ok := medianCI(seq(1, tt.n), benchConfidence)
+		if lo != tt.lo || hi != tt.hi || ok != tt.ok {
+			t.Errorf("medianCI(n=%d) = %v, %v, %v, want %v, %v, %v", tt.n, lo, hi, ok, tt.lo, tt.hi, tt.ok)
+		}
+	}
+}
+
+func TestBinomCDF(t *testing.T) {
+	tests := []struct {
+		k, n int
+		want float64
+	}{
+		{0, 1, 0.5},
+		{1, 10, 11.0 / 1024},
+		{5, 10, 638.0 / 1024},
+		{10, 10, 1},
+	}
+	for _, tt := range tests {
+		if got := binomCDF(tt.k, tt.n); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("binomCDF(%d, %d) = %v, want %v", tt.k, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestGeomean(t *testing.T) {
+	tests := []struct {
+		values []float64
+		want   float64
+	}{
+		{[]float64{1, 4, 16}, 4},
+		{[]float64{2, 8}, 4},
+		{[]float64{1718}, 1718},
+	}
+	for _, tt := range tests {
+		if got := geomean(tt.values); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("geomean(%v) = %v, want %v", tt.values, got, tt.want)
+		}
+	}
+	if got := geomean([]float64{0, 1}); !math.IsNaN(got) {
+		t.Errorf("geomean with 0 = %v, want NaN", got)
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{1.718e-6, "1.718µ"},
+		{1.423e-6, "1.423µ"},
+		{123.4e-9, "123.4n"},
+		{12e-3, "12.00m"},
+		{1, "1.000"},
+		{320, "320.0"},
+		{1234, "1.234k"},
+		{81.92e6, "81.92M"},
+		{1e9, "1.000G"},
+		{0, "0.000"},
+	}
+	for _, tt := range tests {
+		if got := formatValue(tt.value); got != tt.want {
+			t.Errorf("formatValue(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestWriteBenchTable(t *testing.T) {
+	// the rows benchstat prints for these samples.
+	var base, head strings.Builder
+	for _, ns := range []int{1713, 1714, 1715, 1716, 1717, 1719, 1720, 1721, 1722, 1723} {
+		fmt.Fprintf(&base, "BenchmarkEncode-8 1000 %d ns/op 320 B/op 0 allocs/op\n", ns)
+		fmt.Fprintf(&base, "BenchmarkDecode-8 1000 %d ns/op 64 B/op 0 allocs/op\n", ns*2)
+	}
+	for _, ns := range []int{1418, 1419, 1420, 1421, 1422, 1424, 1425, 1426, 1427, 1428} {
+		fmt.Fprintf(&head, "BenchmarkEncode-8 1000 %d ns/op 320 B/op 0 allocs/op\n", ns)
+		fmt.Fprintf(&head, "BenchmarkDecode-8 1000 %d ns/op 64 B/op 0 allocs/op\n", ns*2)
+	}
+	baseSet, err := parseBench(strings.NewReader(base.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	headSet, err := parseBench(strings.NewReader(head.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := writeBenchTable(&out, []string{"go1.21.5", "go1.22.1"}, []*benchSet{baseSet, headSet}); err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	for _, want := range []string{
+		"go1.21.5 go1.22.1",
+		"sec/op sec/op vs base",
+		"Encode-8 1.718µ ± 0% 1.423µ ± 0% -17.17% (p=0.000 n=10)",
+		"Decode-8 3.436µ ± 0% 2.846µ ± 0% -17.17% (p=0.000 n=10)",
+		"geomean 2.430µ 2.012µ -17.17%",
+		"B/op B/op vs base",
+		"Encode-8 320.0 ± 0% 320.0 ± 0% ~ (p=1.000 n=10)",
+		"allocs/op allocs/op vs base",
+		"Encode-8 0.000 ± 0% 0.000 ± 0% ~ (p=1.000 n=10)",
+	} {
+		if !slices.Contains(lines, want) {
+			t.Errorf("missing line %q in\n%s", want, out.String())
+		}
+	}
+}
+
+func TestBenchTestArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{nil, []string{"test", "-run=^$", "-bench=."}},
+		{[]string{"./pkg/...", "-bench", "Encode", "-count", "10"}, []string{"test", "-run=^$", "./pkg/...", "-bench", "Encode", "-count", "10"}},
+		{[]string{"-run=TestX", "./..."}, []string{"test", "-bench=.", "-run=TestX", "./..."}},
+		{[]string{"./...", "-args", "-bench=x"}, []string{"test", "-run=^$", "-bench=.", "./...", "-args", "-bench=x"}},
+	}
+	for _, tt := range tests {
+		if got := benchTestArgs(tt.args); !slices.Equal(got, tt.want) {
+			t.Errorf("benchTestArgs(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func seq(start float64, n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = start + float64(i)
+	}
+	return values
+}
+
+func popcount(v int) int {
+	n := 0
+	for ; v != 0; v &= v - 1 {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	goversion "go/version"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	bisectGoodArg   string
+	bisectBadArg    string
+	bisectScriptArg string
+)
+
+var BisectCmd = &cobra.Command{
+	Use:   "bisect --good 1.21.0 --bad 1.22.3 -- command [args...]",
+	Short: "Find the first release which breaks a command",
+	Long: `Find the first release which breaks a command by binary search.
+The command (or --script) is run with each release like "gvs exec".
+Exit code 0 means good, 125 means the release cannot be tested and is skipped,
+and other codes below 128 mean bad. Codes from 128 abort the bisection.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		if bisectScriptArg != "" {
+			args = append([]string{bisectScriptArg}, args...)
+		}
+		if len(args) == 0 {
+			cmd.Usage()
+			os.Exit(1)
+		}
+		if err := Bisect(ctx, bisectGoodArg, bisectBadArg, args[0], args[1:]); err != nil {
+			fatal(ctx, err)
+		}
+	},
+}
+
+func init() {
+	BisectCmd.Flags().StringVar(&bisectGoodArg, "good", "", "release which is known to be good")
+	BisectCmd.Flags().StringVar(&bisectBadArg, "bad", "", "release which is known to be bad")
+	BisectCmd.Flags().StringVar(&bisectScriptArg, "script", "", "script run with each release instead of a command")
+	BisectCmd.Flags().SetInterspersed(false)
+	BisectCmd.MarkFlagRequired("good")
+	BisectCmd.MarkFlagRequired("bad")
+}
+
+const bisectSkipCode = 125
+
+type bisectState int
+
+const (
+	bisectUntested bisectState = iota
+	bisectGood
+	bisectBad
+	bisectSkip
+)
+
+func (s bisectState) String() string {
+	switch s {
+	case bisectGood:
+		return "good"
+	case bisectBad:
+		return "bad"
+	case bisectSkip:
+		return "skipped"
+	default:
+		return "untested"
+	}
+}
+
+func Bisect(ctx context.Context, good, bad, command string, args []string) error {
+	baseDir, err := checkInit()
+	if err != nil {
+		return err
+	}
+	good, bad = "go"+strings.TrimLeft(good, "vgo"), "go"+strings.TrimLeft(bad, "vgo")
+	if !goversion.IsValid(good) || !goversion.IsValid(bad) {
+		return fmt.Errorf("%s or %s is not a valid release", good, bad)
+	}
+
+	remote, err := fetchRemoteVersions(ctx)
+	if err != nil {
+		return err
+	}
+	goodRelease, badRelease := findRelease(remote, good), findRelease(remote, bad)
+	if goodRelease == nil || badRelease == nil {
+		return fmt.Errorf("%s or %s is not found in releases", good, bad)
+	}
+	if goversion.Compare(goodRelease.Version, badRelease.Version) >= 0 {
+		return fmt.Errorf("good %s must be older than bad %s", goodRelease.Version, badRelease.Version)
+	}
+	// releases are good, the releases between them in order, and bad.
+	releases := []*GoVersion{goodRelease, badRelease}
+	for _, v := range remote {
+		if v.Stable && goversion.Compare(v.Version, goodRelease.Version) > 0 && goversion.Compare(v.Version, badRelease.Version) < 0 {
+			releases = append(releases, v)
+		}
+	}
+	slices.SortFunc(releases, func(l, r *GoVersion) int { return goversion.Compare(l.Version, r.Version) })
+
+	states := make([]bisectState, len(releases))
+	states[0], states[len(states)-1] = bisectGood, bisectBad
+	lo, hi, err := bisectSearch(states, func(next, left int) (bisectState, error) {
+		release := releases[next]
+		infof(ctx, "test %s (%d releases left)", release.Version, left)
+		state, err := bisectRun(ctx, baseDir, release, command, args)
+		if err != nil {
+			return 0, err
+		}
+		infof(ctx, "%s is %s", release.Version, state)
+		return state, nil
+	})
+	if err != nil {
+		return err
+	}
+	if hi-lo == 1 {
+		infof(ctx, "%s is the first bad release", releases[hi].Version)
+		return nil
+	}
+	var names []string
+	for _, release := range releases[lo+1 : hi+1] {
+		names = append(names, release.Version)
+	}
+	infof(ctx, "the first bad release is one of %s, because the others are skipped", strings.Join(names, ", "))
+	return nil
+}
+
+// findRelease returns the release named v. The first release of a minor
+// version is named like go1.20 before Go 1.21 and like go1.21.0 after, so
+// both names find it.
+func findRelease(releases []*GoVersion, v string) *GoVersion {
+	names := []string{v}
+	if lang := goversion.Lang(v); v == lang {
+		names = append(names, v+".0")
+	} else if v == lang+".0" {
+		names = append(names, lang)
+	}
+	for _, release := range releases {
+		if slices.Contains(names, release.Version) {
+			return release
+		}
+	}
+	return nil
+}
+
+// bisectSearch tests releases until the first bad one is found. states
+// starts with a good release and ends with a bad one, and test returns the
+// state of the release at next, with the number of untested releases left.
+// It returns the last good and the first bad index. The releases between
+// them are skipped.
+func bisectSearch(states []bisectState, test func(next, left int) (bisectState, error)) (lo, hi int, err error) {
+	for {
+		hi = slices.Index(states, bisectBad)
+		lo = 0
+		for i, state := range states[:hi] {
+			if state == bisectGood {
+				lo = i
+			}
+		}
+		next := bisectNext(states, lo, hi)
+		if next < 0 {
+			return lo, hi, nil
+		}
+		state, err := test(next, hi-lo-1)
+		if err != nil {
+			return 0, 0, err
+		}
+		states[next] = state
+	}
+}
+
+// bisectNext returns the untested release nearest to the middle of lo and
+// hi, or -1 if all releases between them are tested.
+func bisectNext(states []bisectState, lo, hi int) int {
+	mid := (lo + hi) / 2
+	for d := 0; mid-d > lo || mid+d+1 < hi; d++ {
+		if i := mid - d; i > lo && states[i] == bisectUntested {
+			return i
+		}
+		if i := mid + d + 1; i < hi && states[i] == bisectUntested {
+			return i
+		}
+	}
+	return -1
+}
+
+func bisectRun(ctx context.Context, baseDir string, release *GoVersion, command string, args []string) (bisectState, error) {
+	versionDir := filepath.Join(baseDir, "versions", release.Version)
+	if _, err := os.Stat(versionDir); err != nil {
+		if !os.IsNotExist(err) {
+			return 0, err
+		}
+		if err := downloadGoVersion(ctx, release); err != nil {
+			return 0, fmt.Errorf("download %s: %w", release.Version, err)
+		}
+	}
+
+	env := pinnedEnviron(os.Environ(), versionDir)
+	err := runWithEnv(command, args, env, "", os.Stdout, os.Stderr)
+	var extErr *exec.ExitError
+	switch {
+	case err == nil:
+		return bisectGood, nil
+	case errors.As(err, &extErr):
+		code := exitCode(extErr)
+		switch {
+		case code == bisectSkipCode:
+			return bisectSkip, nil
+		case code >= 128:
+			return 0, fmt.Errorf("abort bisect: %s exited with %d", command, code)
+		default:
+			return bisectBad, nil
+		}
+	default:
+		return 0, err
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBisectNext(t *testing.T) {
+	const (
+		u = bisectUntested
+		g = bisectGood
+		b = bisectBad
+		s = bisectSkip
+	)
+	tests := []struct {
+		name   string
+		states []bisectState
+		lo, hi int
+		want   int
+	}{
+		{name: "middle", states: []bisectState{g, u, u, u, b}, lo: 0, hi: 4, want: 2},
+		{name: "lower middle of even releases", states: []bisectState{g, u, u, u, u, b}, lo: 0, hi: 5, want: 2},
+		{name: "next to a skipped middle", states: []bisectState{g, u, s, u, b}, lo: 0, hi: 4, want: 3},
+		{name: "below skipped releases", states: []bisectState{g, u, s, s, b}, lo: 0, hi: 4, want: 1},
+		{name: "all skipped", states: []bisectState{g, s, s, b}, lo: 0, hi: 3, want: -1},
+		{name: "adjacent", states: []bisectState{g, b}, lo: 0, hi: 1, want: -1},
+		{name: "inside narrowed range", states: []bisectState{g, g, u, u, b, b}, lo: 1, hi: 4, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bisectNext(tt.states, tt.lo, tt.hi); got != tt.want {
+				t.Errorf("bisectNext(%v, %d, %d) = %d, want %d", tt.states, tt.lo, tt.hi, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBisectSearch(t *testing.T) {
+	tests := []struct {
+		name     string
+		releases int
+		firstBad int
+		skipped  []int
+		wantLo   int
+		wantHi   int
+	}{
+		{name: "first bad in the middle", releases: 10, firstBad: 6, wantLo: 5, wantHi: 6},
+		{name: "first bad next to good", releases: 10, firstBad: 1, wantLo: 0, wantHi: 1},
+		{name: "only bad is bad", releases: 10, firstBad: 9, wantLo: 8, wantHi: 9},
+		{name: "no releases between", releases: 2, firstBad: 1, wantLo: 0, wantHi: 1},
+		{name: "skipped good release is passed over", releases: 10, firstBad: 6, skipped: []int{4, 5}, wantLo: 3, wantHi: 6},
+		{name: "skipped first bad release", releases: 10, firstBad: 6, skipped: []int{6}, wantLo: 5, wantHi: 7},
+		{name: "all skipped", releases: 6, firstBad: 3, skipped: []int{1, 2, 3, 4}, wantLo: 0, wantHi: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			states := make([]bisectState, tt.releases)
+			states[0], states[len(states)-1] = bisectGood, bisectBad
+			tested := map[int]bool{}
+			lo, hi, err := bisectSearch(states, func(next, _ int) (bisectState, error) {
+				if tested[next] {
+					t.Fatalf("release %d is tested twice", next)
+				}
+				tested[next] = true
+				switch {
+				case slices.Contains(tt.skipped, next):
+					return bisectSkip, nil
+				case next >= tt.firstBad:
+					return bisectBad, nil
+				default:
+					return bisectGood, nil
+				}
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lo != tt.wantLo || hi != tt.wantHi {
+				t.Errorf("bisectSearch() = %d, %d, want %d, %d", lo, hi, tt.wantLo, tt.wantHi)
+			}
+		})
+	}
+}
+
+func TestBisectRunDownloadError(t *testing.T) {
+	baseDir, _ := setupHome(t)
+	_, err := bisectRun(testContext(), baseDir, &GoVersion{Version: "go1.21.3"}, "true", nil)
+	if err == nil || !strings.Contains(err.Error(), "download go1.21.3") {
+		t.Fatalf("bisectRun() error = %v, want the download error", err)
+	}
+}
+
+func TestFindRelease(t *testing.T) {
+	releases := []*GoVersion{{Version: "go1.20"}, {Version: "go1.20.1"}, {Version: "go1.21.0"}, {Version: "go1.21rc2"}}
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"go1.20", "go1.20"},
+		{"go1.20.0", "go1.20"},
+		{"go1.20.1", "go1.20.1"},
+		{"go1.21", "go1.21.0"},
+		{"go1.21.0", "go1.21.0"},
+		{"go1.21rc2", "go1.21rc2"},
+		{"go1.22.0", ""},
+	}
+	for _, tt := range tests {
+		var got string
+		if release := findRelease(releases, tt.version); release != nil {
+			got = release.Version
+		}
+		if got != tt.want {
+			t.Errorf("findRelease(%s) = %q, want %q", tt.version, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const cacheDir = "cache"
+
+// racyDuration is how close to now a watched file may have been modified
+// before the resolution is not cached, because a following change within
+// the mtime granularity of the file system would go unnoticed.
+const racyDuration = 2 * time.Second
+
+// fileStamp records the state of a path which was looked at while resolving.
+// A missing path is recorded too, so that creating it invalidates the cache.
+type fileStamp struct {
+	Path    string `json:"path"`
+	Exists  bool   `json:"exists"`
+	ModTime int64  `json:"mod_time,omitempty"`
+	Size    int64  `json:"size,omitempty"`
+	Mode    uint32 `json:"mode,omitempty"`
+}
+
+type resolveCacheEntry struct {
+	Source *versionCandidate `json:"source"`
+	Stamps []fileStamp       `json:"stamps"`
+}
+
+func stampFile(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{Path: path}
+	}
+	return fileStamp{
+		Path:    path,
+		Exists:  true,
+		ModTime: info.ModTime().UnixNano(),
+		Size:    info.Size(),
+		Mode:    uint32(info.Mode()),
+	}
+}
+
+// resolveCachePath returns the cache file for resolving dir with modFile.
+// GVS_* variables are part of the key because they change the configuration.
+func resolveCachePath(baseDir, dir, modFile string) (string, error) {
+	directory, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	key := []string{directory, modFile}
+	for _, env := range os.Environ() {
+		if strings.HasPrefix(env, "GVS_") {
+			key = append(key, env)
+		}
+	}
+	slices.Sort(key[2:])
+	sum := sha256.Sum256([]byte(strings.Join(key, "\x00")))
+	return filepath.Join(baseDir, cacheDir, "resolve", hex.EncodeToString(sum[:16])+".json"), nil
+}
+
+func readResolveCache(ctx context.Context, path string) (*versionCandidate, bool) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var entry resolveCacheEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		debugf(ctx, "decode %s: %v", path, err)
+		return nil, false
+	}
+	if entry.Source == nil || entry.Source.Version == "" {
+		return nil, false
+	}
+	for _, stamp := range entry.Stamps {
+		if stampFile(stamp.Path) != stamp {
+			debugf(ctx, "cache is invalidated by %s", stamp.Path)
+			return nil, false
+		}
+	}
+	return entry.Source, true
+}
+
+func writeResolveCache(ctx context.Context, path string, res *resolution) {
+	entry := resolveCacheEntry{Source: res.Source}
+	now := time.Now()
+	for _, watched := range res.watched {
+		stamp := stampFile(watched)
+		if stamp.Exists && now.Sub(time.Unix(0, stamp.ModTime)) < racyDuration {
+			debugf(ctx, "%s is modified recently. skip cache", watched)
+			return
+		}
+		entry.Stamps = append(entry.Stamps, stamp)
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		debugf(ctx, "encode cache: %v", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		debugf(ctx, "create cache dir: %v", err)
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "*.tmp")
+	if err != nil {
+		debugf(ctx, "create cache: %v", err)
+		return
+	}
+	_, err = tmp.Write(b)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		debugf(ctx, "write cache: %v", err)
+	}
+}
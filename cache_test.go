@@ -0,0 +1,205 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResolveCacheInvalidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		dir    string
+		setup  func(t *testing.T, baseDir, project string)
+		change func(t *testing.T, baseDir, project string)
+		before string
+		after  string
+	}{
+		{
+			name: "nearer .go-version is created",
+			dir:  "sub",
+			setup: func(t *testing.T, baseDir, project string) {
+				writeFile(t, filepath.Join(project, "go.mod"), "module example.com/p\n\ngo 1.21\n")
+				writeFile(t, filepath.Join(project, "sub", "main.go"), "package main\n")
+			},
+			change: func(t *testing.T, baseDir, project string) {
+				writeFile(t, filepath.Join(project, "sub", localVersionFile), "1.22.1")
+			},
+			before: "1.21",
+			after:  "1.22.1",
+		},
+		{
+			name: "nearer .go-version is removed",
+			dir:  "sub",
+			setup: func(t *testing.T, baseDir, project string) {
+				writeFile(t, filepath.Join(project, localVersionFile), "1.21")
+				writeFile(t, filepath.Join(project, "sub", localVersionFile), "1.22.1")
+			},
+			change: func(t *testing.T, baseDir, project string) {
+				if err := os.Remove(filepath.Join(project, "sub", localVersionFile)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			before: "1.22.1",
+			after:  "1.21",
+		},
+		{
+			name: "nearer go.mod is created in a workspace",
+			dir:  filepath.Join("lib", "internal"),
+			setup: func(t *testing.T, baseDir, project string) {
+				writeFile(t, filepath.Join(project, "go.work"), "go 1.22.1\n")
+				writeFile(t, filepath.Join(project, "lib", "internal", "x.go"), "package internal\n")
+			},
+			change: func(t *testing.T, baseDir, project string) {
+				writeFile(t, filepath.Join(project, "lib", "go.mod"), "module example.com/lib\n\ngo 1.21\n")
+			},
+			before: "1.22.1",
+			after:  "1.21",
+		},
+		{
+			name: "global version is edited",
+			setup: func(t *testing.T, baseDir, project string) {
+				writeFile(t, filepath.Join(baseDir, globalVersionFile), "1.21")
+			},
+			change: func(t *testing.T, baseDir, project string) {
+				writeFileAt(t, filepath.Join(baseDir, globalVersionFile), "1.22", time.Now().Add(-30*time.Minute))
+			},
+			before: "1.21",
+			after:  "1.22",
+		},
+		{
+			name: "config is changed",
+			setup: func(t *testing.T, baseDir, project string) {
+				writeFile(t, filepath.Join(baseDir, globalVersionFile), "1.22.1")
+				writeFile(t, filepath.Join(project, localVersionFile), "1.21")
+			},
+			change: func(t *testing.T, baseDir, project string) {
+				writeFile(t, filepath.Join(baseDir, configFile), "sources = global\n")
+			},
+			before: "1.21",
+			after:  "1.22.1",
+		},
+		{
+			name: "ceiling directories in config are changed",
+			dir:  "sub",
+			setup: func(t *testing.T, baseDir, project string) {
+				t.Setenv("GVS_CEILING_DIRECTORIES", "")
+				os.Unsetenv("GVS_CEILING_DIRECTORIES")
+				writeFile(t, filepath.Join(baseDir, configFile), "ceiling-directories = "+filepath.Dir(project)+"\n")
+				writeFile(t, filepath.Join(baseDir, globalVersionFile), "1.22.1")
+				writeFile(t, filepath.Join(project, "go.mod"), "module example.com/p\n\ngo 1.21\n")
+				writeFile(t, filepath.Join(project, "sub", "main.go"), "package main\n")
+			},
+			change: func(t *testing.T, baseDir, project string) {
+				writeFile(t, filepath.Join(baseDir, configFile), "ceiling-directories = "+project+"\n")
+			},
+			before: "1.21",
+			after:  "1.22.1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := testContext()
+			baseDir, project := setupHome(t)
+			tt.setup(t, baseDir, project)
+			dir := filepath.Join(project, tt.dir)
+
+			assertVersion(t, baseDir, dir, tt.before)
+			cachePath, err := resolveCachePath(baseDir, dir, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := readResolveCache(ctx, cachePath); !ok {
+				t.Fatal("resolution is not cached")
+			}
+
+			tt.change(t, baseDir, project)
+			if _, ok := readResolveCache(ctx, cachePath); ok {
+				t.Fatal("cache is not invalidated")
+			}
+			assertVersion(t, baseDir, dir, tt.after)
+		})
+	}
+}
+
+func TestResolveCacheSkipsRacyFiles(t *testing.T) {
+	ctx := testContext()
+	baseDir, project := setupHome(t)
+	writeFileAt(t, filepath.Join(project, localVersionFile), "1.21", time.Now())
+
+	assertVersion(t, baseDir, project, "1.21")
+	cachePath, err := resolveCachePath(baseDir, project, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cachePath); !os.IsNotExist(err) {
+		t.Fatalf("resolution with a recently modified file is cached: %v", err)
+	}
+	if _, ok := readResolveCache(ctx, cachePath); ok {
+		t.Fatal("cache is read")
+	}
+}
+
+func TestResolveCacheKeyIncludesEnv(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		value  func(project string) string
+		before string
+		after  string
+	}{
+		{
+			name:   "sources",
+			key:    "GVS_SOURCES",
+			value:  func(string) string { return "global" },
+			before: "1.21",
+			after:  "1.22.1",
+		},
+		{
+			name:   "ceiling directories",
+			key:    "GVS_CEILING_DIRECTORIES",
+			value:  func(project string) string { return project },
+			before: "1.21",
+			after:  "1.22.1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseDir, project := setupHome(t)
+			writeFile(t, filepath.Join(baseDir, globalVersionFile), "1.22.1")
+			writeFile(t, filepath.Join(project, localVersionFile), "1.21")
+			dir := filepath.Join(project, "sub")
+			if err := os.Mkdir(dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			assertVersion(t, baseDir, dir, tt.before)
+			before, err := resolveCachePath(baseDir, dir, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			t.Setenv(tt.key, tt.value(project))
+			after, err := resolveCachePath(baseDir, dir, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if before == after {
+				t.Fatalf("cache path does not change with %s", tt.key)
+			}
+			assertVersion(t, baseDir, dir, tt.after)
+		})
+	}
+}
+
+func assertVersion(t *testing.T, baseDir, dir, want string) {
+	t.Helper()
+	candidate, err := decideCandidate(testContext(), baseDir, dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if candidate.Version != want {
+		t.Fatalf("version = %q, want %q", candidate.Version, want)
+	}
+}
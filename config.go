@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const configFile = "config"
+
+// config is read from $HOME/.gvs/config. Each line is "key = value" and
+// lines starting with '#' are comments. Every key can be overridden by the
+// environment variable GVS_<KEY>, e.g. GVS_SOURCES for "sources".
+type config struct {
+	values map[string]string
+}
+
+func loadConfig(baseDir string) (*config, error) {
+	c := &config{values: make(map[string]string)}
+	file, err := os.Open(filepath.Join(baseDir, configFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected key = value", file.Name(), line)
+		}
+		c.values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", file.Name(), err)
+	}
+	return c, nil
+}
+
+func configEnvName(key string) string {
+	return "GVS_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
+
+func (c *config) get(key string) string {
+	if v, ok := os.LookupEnv(configEnvName(key)); ok {
+		return strings.TrimSpace(v)
+	}
+	return c.values[key]
+}
+
+func (c *config) list(key string) []string {
+	var values []string
+	for _, v := range strings.Split(c.get(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func (c *config) bool(key string, def bool) (bool, error) {
+	v := c.get(key)
+	if v == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", key, err)
+	}
+	return b, nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	currentJSONArg bool
+	whichJSONArg   bool
+)
+
+var CurrentCmd = &cobra.Command{
+	Use:   "current",
+	Short: "Explain which version is selected and why",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		ctx := cmd.Context()
+		if err := outputExplanation(ctx, "", currentJSONArg); err != nil {
+			fatal(ctx, err)
+		}
+	},
+}
+
+var WhichCmd = &cobra.Command{
+	Use:   "which [command]",
+	Short: "Show the binary path of the selected version",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		command := "go"
+		if len(args) > 0 {
+			command = args[0]
+		}
+		if err := outputExplanation(ctx, command, whichJSONArg); err != nil {
+			fatal(ctx, err)
+		}
+	},
+}
+
+func init() {
+	CurrentCmd.Flags().BoolVar(&currentJSONArg, "json", false, "output JSON")
+	WhichCmd.Flags().BoolVar(&whichJSONArg, "json", false, "output JSON")
+}
+
+type explanation struct {
+	*resolution
+	Installed string `json:"installed,omitempty"`
+	Binary    string `json:"binary,omitempty"`
+}
+
+// Explain resolves the version like Run does, without downloading, and
+// records every step of the resolution. command may be empty.
+func Explain(ctx context.Context, command string) (*explanation, error) {
+	baseDir, err := checkInit()
+	if err != nil {
+		return nil, err
+	}
+	r, err := newResolver(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	res, err := r.resolve(ctx, ".", "")
+	if err != nil && !errors.Is(err, ErrNotFoundGlobalVersion) {
+		return nil, err
+	}
+	ex := &explanation{resolution: res}
+	if res.Version == "" {
+		return ex, nil
+	}
+
+	parsedVersion, err := parseVersionString(res.Version)
+	if err != nil {
+		return nil, err
+	}
+	name, err := findLocalVersion(baseDir, parsedVersion)
+	if err != nil {
+		if errors.Is(err, ErrNotFoundLocalVersion) {
+			return ex, nil
+		}
+		return nil, err
+	}
+	ex.Installed = filepath.Join(baseDir, "versions", name)
+	if command != "" {
+		ex.Binary, err = findCommand(ex.Installed, command)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ex, nil
+}
+
+func outputExplanation(ctx context.Context, command string, asJSON bool) error {
+	ex, err := Explain(ctx, command)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(ex)
+	} else {
+		err = writeExplanation(os.Stdout, ex)
+	}
+	if err != nil || ex.Installed == "" {
+		return err
+	}
+	baseDir, err := checkInit()
+	if err != nil {
+		return err
+	}
+	return checkSupport(ctx, baseDir, ex.Installed, true)
+}
+
+func writeExplanation(w io.Writer, ex *explanation) error {
+	var buf strings.Builder
+	if ex.Source == nil {
+		buf.WriteString("version:   (none)\n")
+	} else {
+		fmt.Fprintf(&buf, "version:   %s (%s)\n", ex.Version, candidateLocation(ex.Source))
+	}
+	if ex.Installed == "" {
+		buf.WriteString("installed: (not installed)\n")
+	} else {
+		fmt.Fprintf(&buf, "installed: %s\n", ex.Installed)
+	}
+	if ex.Binary != "" {
+		fmt.Fprintf(&buf, "binary:    %s\n", ex.Binary)
+	}
+
+	buf.WriteString("\ncandidates:\n")
+	tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	for _, candidate := range ex.Candidates {
+		mark := " "
+		if candidate.Selected {
+			mark = "*"
+		}
+		v := candidate.Version
+		if v == "" {
+			v = "(no version)"
+		}
+		fmt.Fprintf(tw, "%s %s\t%s\t%s\n", mark, candidate.Source, candidateLocation(candidate), v)
+	}
+	tw.Flush()
+
+	buf.WriteString("\ndirectories:\n")
+	for _, dir := range ex.Directories {
+		fmt.Fprintf(&buf, "  %s\n", dir)
+	}
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+func candidateLocation(c *versionCandidate) string {
+	switch {
+	case c.File == "":
+		return c.Source
+	case c.Line == 0:
+		return c.File
+	default:
+		return fmt.Sprintf("%s:%d", c.File, c.Line)
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	goversion "go/version"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var DoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose problems of the installation and PATH",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		ctx := cmd.Context()
+		findings := Doctor(ctx)
+		writeFindings(os.Stdout, findings)
+		for _, f := range findings {
+			if f.broken {
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+// finding is a result of a check of doctor. A finding which is not ok
+// explains how to fix it.
+type finding struct {
+	ok      bool
+	broken  bool
+	message string
+	fix     string
+}
+
+func okf(format string, args ...any) finding {
+	return finding{ok: true, message: fmt.Sprintf(format, args...)}
+}
+
+func warnFinding(message, fix string) finding {
+	return finding{message: message, fix: fix}
+}
+
+func brokenFinding(message, fix string) finding {
+	return finding{broken: true, message: message, fix: fix}
+}
+
+// Doctor checks the installation of gvs and the environment.
+func Doctor(_ context.Context) []finding {
+	baseDir, err := checkInit()
+	if err != nil {
+		return []finding{brokenFinding(fmt.Sprintf("gvs is not initialized: %v", err), "run `gvs init`")}
+	}
+	shimDir := filepath.Join(baseDir, "bin")
+
+	var findings []finding
+	findings = append(findings, checkPath(baseDir, shimDir)...)
+	findings = append(findings, checkShims(shimDir)...)
+	findings = append(findings, checkGoroot(baseDir))
+	findings = append(findings, checkVersions(baseDir)...)
+	findings = append(findings, checkGlobalVersion(baseDir))
+	return findings
+}
+
+// checkPath checks that the go found first on PATH is the shim, or the
+// version activated by the hook.
+func checkPath(baseDir, shimDir string) []finding {
+	path, pathErr := lookPathIn("go", os.Environ())
+	if active := os.Getenv(envActiveKey); active != "" {
+		if pathErr == nil && filepath.Dir(filepath.Dir(path)) == filepath.Clean(active) {
+			return []finding{okf("%s is activated by the hook", active)}
+		}
+		return []finding{brokenFinding(
+			fmt.Sprintf("the hook activated %s, but go on PATH is %q", active, path),
+			"load `gvs hook` last in your shell profile so that nothing changes PATH after it",
+		)}
+	}
+
+	inPath := false
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if filepath.Clean(dir) == shimDir {
+			inPath = true
+		}
+	}
+	if !inPath && os.Getenv(envHookKey) != "" {
+		if pathErr != nil {
+			return []finding{warnFinding(
+				"the hook is loaded, but go is not found on PATH outside projects",
+				fmt.Sprintf("add `export PATH=\"%s:$PATH\"` to your shell profile to use the global version outside projects", shimDir),
+			)}
+		}
+		return []finding{okf("the hook is loaded and no project version is active here; go is %s", path)}
+	}
+	if !inPath {
+		return []finding{brokenFinding(
+			shimDir+" is not on PATH and no version is activated by the hook",
+			fmt.Sprintf("add `export PATH=\"%s:$PATH\"` to your shell profile, or run `gvs init --write`", shimDir),
+		)}
+	}
+
+	if pathErr != nil {
+		return []finding{brokenFinding("go is not found on PATH", "run `gvs rehash`")}
+	}
+	dir := filepath.Dir(path)
+	switch {
+	case dir == shimDir:
+		return []finding{okf("the shims in %s are first on PATH", shimDir)}
+	case strings.HasPrefix(dir, filepath.Join(baseDir, "versions")+string(filepath.Separator)):
+		return []finding{brokenFinding(
+			path+" shadows the shim "+filepath.Join(shimDir, "go")+" without the hook",
+			fmt.Sprintf("remove %s from PATH in your shell profile", dir),
+		)}
+	default:
+		return []finding{brokenFinding(
+			path+" shadows the shim "+filepath.Join(shimDir, "go"),
+			fmt.Sprintf("put %s before %s in PATH", shimDir, dir),
+		)}
+	}
+}
+
+// checkShims checks that the shims run gvs.
+func checkShims(shimDir string) []finding {
+	entries, err := os.ReadDir(shimDir)
+	if err != nil {
+		return []finding{brokenFinding(fmt.Sprintf("read %s: %v", shimDir, err), "run `gvs init`")}
+	}
+	var findings []finding
+	var scripts, broken []string
+	for _, entry := range entries {
+		path := filepath.Join(shimDir, entry.Name())
+		if _, err := os.Stat(path); err != nil {
+			broken = append(broken, entry.Name())
+			continue
+		}
+		if b, err := os.ReadFile(path); err == nil && strings.HasPrefix(string(b), scriptShimPrefix) {
+			scripts = append(scripts, entry.Name())
+		}
+	}
+	if len(broken) > 0 {
+		findings = append(findings, brokenFinding(
+			"broken shims: "+strings.Join(broken, ", "),
+			"run `gvs rehash` to recreate them",
+		))
+	}
+	if len(scripts) > 0 {
+		if _, err := lookPathIn("gvs", os.Environ()); err != nil {
+			findings = append(findings, brokenFinding(
+				"the script shims ("+strings.Join(scripts, ", ")+") run gvs, but gvs is not found on PATH",
+				"put the directory of the gvs binary on PATH, or run `gvs rehash` to link the shims to gvs",
+			))
+		}
+	}
+	if len(findings) == 0 {
+		findings = append(findings, okf("%d shims are valid", len(entries)))
+	}
+	return findings
+}
+
+// checkGoroot checks that GOROOT is not set except by the hook.
+func checkGoroot(baseDir string) finding {
+	goroot, ok := os.LookupEnv("GOROOT")
+	if !ok || goroot == "" {
+		return okf("GOROOT is not set")
+	}
+	if active := os.Getenv(envActiveKey); active != "" && filepath.Clean(goroot) == filepath.Clean(active) {
+		return okf("GOROOT is set by the hook")
+	}
+	if strings.HasPrefix(goroot, filepath.Join(baseDir, "versions")+string(filepath.Separator)) {
+		return warnFinding(
+			"GOROOT is set to "+goroot+", which the shims override",
+			"remove GOROOT from your shell profile",
+		)
+	}
+	return brokenFinding(
+		"GOROOT is set to "+goroot+", which may make go use another standard library",
+		"remove GOROOT from your shell profile and run `unset GOROOT`",
+	)
+}
+
+// checkVersions checks that the installed versions are complete.
+func checkVersions(baseDir string) []finding {
+	versionsDir := filepath.Join(baseDir, "versions")
+	entries, err := os.ReadDir(versionsDir)
+	if err != nil {
+		return []finding{brokenFinding(fmt.Sprintf("read %s: %v", versionsDir, err), "run `gvs init`")}
+	}
+	var findings []finding
+	for _, entry := range entries {
+		name := entry.Name()
+		dir := filepath.Join(versionsDir, name)
+		if !goversion.IsValid(name) {
+			findings = append(findings, warnFinding(
+				dir+" is not a version",
+				"remove "+dir,
+			))
+			continue
+		}
+		if problem := versionProblem(dir, name); problem != "" {
+			v := strings.TrimPrefix(name, "go")
+			findings = append(findings, brokenFinding(
+				name+" is broken: "+problem,
+				fmt.Sprintf("reinstall it by `gvs uninstall --force %s && gvs download %s`", v, v),
+			))
+		}
+	}
+	if len(findings) == 0 {
+		findings = append(findings, okf("%d versions are installed", len(entries)))
+	}
+	return findings
+}
+
+// versionProblem returns why the installed version is incomplete, e.g. by
+// an interrupted extraction.
+func versionProblem(dir, name string) string {
+	for _, command := range shimCommands {
+		info, err := os.Stat(filepath.Join(dir, "bin", command))
+		if err != nil {
+			if info, err = os.Stat(filepath.Join(dir, "bin", command+".exe")); err != nil {
+				return "bin/" + command + " is missing"
+			}
+		}
+		if !isExecutable(info) {
+			return "bin/" + command + " is not executable"
+		}
+	}
+	for _, path := range []string{"src", filepath.Join("pkg", "tool")} {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			return path + " is missing"
+		}
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "VERSION"))
+	if err != nil {
+		return "VERSION is missing"
+	}
+	if line, _, _ := strings.Cut(string(b), "\n"); strings.TrimSpace(line) != name {
+		return fmt.Sprintf("VERSION is %q", strings.TrimSpace(line))
+	}
+	return ""
+}
+
+// checkGlobalVersion checks that the global version file has a version.
+func checkGlobalVersion(baseDir string) finding {
+	path := filepath.Join(baseDir, globalVersionFile)
+	v, err := readGlobalVersion(baseDir)
+	if err != nil {
+		if errors.Is(err, ErrNotFoundGlobalVersion) {
+			return warnFinding("the global version is not set", "run `gvs use 1.22` with the version to use")
+		}
+		return brokenFinding(fmt.Sprintf("read %s: %v", path, err), "run `gvs use 1.22` with the version to use")
+	}
+	if strings.ContainsAny(v, " \t") || !goversion.IsValid("go"+strings.TrimLeft(v, "vgo")) {
+		return brokenFinding(
+			fmt.Sprintf("%s has an invalid version %q", path, v),
+			"run `gvs use 1.22` with the version to use",
+		)
+	}
+	if installedGlobalVersion(baseDir) == "" {
+		return warnFinding(
+			"the global version "+v+" is not installed",
+			"run `gvs download "+v+"`, or it is downloaded on the first run",
+		)
+	}
+	return okf("the global version is %s", v)
+}
+
+func writeFindings(w io.Writer, findings []finding) {
+	for _, f := range findings {
+		switch {
+		case f.ok:
+			fmt.Fprintf(w, "ok    %s\n", f.message)
+		case f.broken:
+			fmt.Fprintf(w, "FAIL  %s\n", f.message)
+		default:
+			fmt.Fprintf(w, "WARN  %s\n", f.message)
+		}
+		if f.fix != "" {
+			fmt.Fprintf(w, "      fix: %s\n", f.fix)
+		}
+	}
+}
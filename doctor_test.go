@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func findingState(f finding) string {
+	switch {
+	case f.ok:
+		return "ok"
+	case f.broken:
+		return "FAIL"
+	default:
+		return "WARN"
+	}
+}
+
+func TestCheckPath(t *testing.T) {
+	baseDir, project := setupHome(t, "go1.22.1")
+	shimDir := filepath.Join(baseDir, "bin")
+	writeFile(t, filepath.Join(shimDir, "go"), "")
+	systemDir := filepath.Join(project, "system")
+	writeFile(t, filepath.Join(systemDir, "go"), "")
+	for _, path := range []string{filepath.Join(shimDir, "go"), filepath.Join(systemDir, "go")} {
+		if err := os.Chmod(path, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	versionDir := filepath.Join(baseDir, "versions", "go1.22.1")
+
+	tests := []struct {
+		name   string
+		path   []string
+		hook   bool
+		active string
+		want   string
+	}{
+		{"shims first", []string{shimDir, systemDir}, false, "", "ok"},
+		{"shadowed shims", []string{systemDir, shimDir}, false, "", "FAIL"},
+		{"no shims", []string{systemDir}, false, "", "FAIL"},
+		{"hook outside projects", []string{systemDir}, true, "", "ok"},
+		{"hook without go outside projects", []string{project}, true, "", "WARN"},
+		{"hook in a project", []string{filepath.Join(versionDir, "bin"), systemDir}, true, versionDir, "ok"},
+		{"hook shadowed in a project", []string{systemDir, filepath.Join(versionDir, "bin")}, true, versionDir, "FAIL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PATH", strings.Join(tt.path, string(filepath.ListSeparator)))
+			t.Setenv(envHookKey, "")
+			if tt.hook {
+				t.Setenv(envHookKey, "1")
+			}
+			t.Setenv(envActiveKey, tt.active)
+			findings := checkPath(baseDir, shimDir)
+			if len(findings) != 1 || findingState(findings[0]) != tt.want {
+				t.Errorf("checkPath() = %+v, want %s", findings, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckGlobalVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"missing", "", "WARN"},
+		{"empty", "\n\n", "WARN"},
+		{"version", "1.22.1\n", "ok"},
+		{"first non-empty line", "\n1.22.1\n1.21\n", "ok"},
+		{"not installed", "1.21\n", "WARN"},
+		{"invalid", "1.22 beta\n", "FAIL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseDir, _ := setupHome(t, "go1.22.1")
+			if tt.content != "" {
+				writeFile(t, filepath.Join(baseDir, globalVersionFile), tt.content)
+			}
+			if got := checkGlobalVersion(baseDir); findingState(got) != tt.want {
+				t.Errorf("checkGlobalVersion() = %+v, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,9 +5,9 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
+	goversion "go/version"
 	"io"
 	"net/http"
 	"net/url"
@@ -53,7 +53,6 @@ type GoVersion struct {
 		Size     int    `json:"size"`
 		Kind     string `json:"kind"`
 	} `json:"files"`
-	priority int
 }
 
 const (
@@ -62,29 +61,13 @@ const (
 )
 
 func findTarget(ctx context.Context, v *version) (*GoVersion, error) {
-	r, err := http.NewRequestWithContext(ctx, http.MethodGet, goVersionURL, nil)
+	versions, err := fetchRemoteVersions(ctx)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := http.DefaultClient.Do(r)
-	if err != nil {
-		return nil, err
-	}
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		return nil, fmt.Errorf("status is %d. response %s", resp.StatusCode, body)
-	}
-
-	var versions []*GoVersion
-	if err := json.NewDecoder(resp.Body).Decode(&versions); err != nil {
-		return nil, fmt.Errorf("decode response body: %w", err)
-	}
 	var filtered []*GoVersion
 	for _, version := range versions {
-		splits := strings.Split(strings.TrimLeft(version.Version, "go"), ".")
-		if compareVersionString(splits, v) {
-			version.priority = calcPriority(splits)
+		if matchVersion(version.Version, v) {
 			filtered = append(filtered, version)
 		}
 	}
@@ -93,7 +76,7 @@ func findTarget(ctx context.Context, v *version) (*GoVersion, error) {
 	}
 
 	slices.SortFunc(filtered, func(l, r *GoVersion) int {
-		return r.priority - l.priority
+		return goversion.Compare(r.Version, l.Version)
 	})
 
 	return filtered[0], nil
@@ -109,16 +92,24 @@ func (g *GoVersion) getDownloadFile() string {
 }
 
 func Download(ctx context.Context, v *version) error {
-	base, err := checkInit()
+	goversion, err := findTarget(ctx, v)
 	if err != nil {
 		return err
 	}
-	goversion, err := findTarget(ctx, v)
+	return downloadGoVersion(ctx, goversion)
+}
+
+// downloadGoVersion downloads and installs exactly goversion.
+func downloadGoVersion(ctx context.Context, goversion *GoVersion) error {
+	base, err := checkInit()
 	if err != nil {
 		return err
 	}
 
 	file := goversion.getDownloadFile()
+	if file == "" {
+		return fmt.Errorf("%s is not available for %s/%s", goversion.Version, runtime.GOOS, runtime.GOARCH)
+	}
 	url, err := url.JoinPath(downloadURL, file)
 	if err != nil {
 		return err
@@ -145,7 +136,7 @@ func Download(ctx context.Context, v *version) error {
 		return err
 	}
 
-	return nil
+	return Rehash(ctx)
 }
 
 func extract(file *os.File) (string, error) {
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/modfile"
+)
+
+var (
+	eachFilterArg   []string
+	eachParallelArg int
+	eachCaptureArg  bool
+)
+
+var EachCmd = &cobra.Command{
+	Use:   "each [--filter glob] -- command [args...]",
+	Short: "Run a command in every module with the version of each module",
+	Long: `Run a command in every module under the current directory.
+The version of each module is decided from its directory, and the command
+runs in the directory with the version. The filter glob matches the
+directory (relative to the current directory) or the module path.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		jobs, err := Each(ctx, eachFilterArg, args[0], args[1:])
+		if err != nil {
+			exitOnError(ctx, err)
+		}
+		writeEachSummary(os.Stdout, jobs)
+		for _, job := range jobs {
+			if job.Err != nil {
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+func init() {
+	EachCmd.Flags().StringSliceVar(&eachFilterArg, "filter", nil, "glob of modules to run (comma separated)")
+	EachCmd.Flags().IntVarP(&eachParallelArg, "parallel", "p", runtime.NumCPU(), "number of modules run at once")
+	EachCmd.Flags().BoolVar(&eachCaptureArg, "capture", false, "print the output of each module at once when it finishes instead of prefixing lines")
+	EachCmd.Flags().SetInterspersed(false)
+}
+
+func Each(ctx context.Context, filters []string, command string, args []string) ([]*parallelJob, error) {
+	baseDir, err := checkInit()
+	if err != nil {
+		return nil, err
+	}
+	modules, err := findModules(".")
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []*parallelJob
+	for _, module := range modules {
+		if !matchModule(module, filters) {
+			continue
+		}
+		versionDir, err := installedVersionDir(ctx, baseDir, autoVersion, module.dir, "")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", module.dir, err)
+		}
+		jobs = append(jobs, &parallelJob{
+			Label:   filepath.ToSlash(module.dir),
+			Version: filepath.Base(versionDir),
+			Dir:     module.dir,
+			Env:     toolchainEnviron(os.Environ(), versionDir),
+		})
+	}
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("no modules are found")
+	}
+
+	runParallel(jobs, command, args, eachParallelArg, eachCaptureArg)
+	return jobs, nil
+}
+
+type goModule struct {
+	dir  string
+	path string
+}
+
+// findModules returns the directories containing go.mod under root. It
+// skips the directories the go command ignores.
+func findModules(root string) ([]goModule, error) {
+	var modules []goModule
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != root && ignoredDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != "go.mod" {
+			return nil
+		}
+		m := goModule{dir: filepath.Dir(p)}
+		if b, err := os.ReadFile(p); err == nil {
+			m.path = modfile.ModulePath(b)
+		}
+		modules = append(modules, m)
+		return nil
+	})
+	return modules, err
+}
+
+// ignoredDir reports whether the go command ignores the directory name.
+func ignoredDir(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") ||
+		name == "testdata" || name == "vendor" || name == "node_modules"
+}
+
+func matchModule(m goModule, filters []string) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, filter := range filters {
+		if ok, _ := path.Match(filter, filepath.ToSlash(m.dir)); ok {
+			return true
+		}
+		if ok, _ := path.Match(filter, m.path); ok && m.path != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func writeEachSummary(w io.Writer, jobs []*parallelJob) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nMODULE\tVERSION\tRESULT\tTIME")
+	for _, job := range jobs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", job.Label, job.Version, resultString(job.Err), job.Duration.Round(time.Millisecond))
+	}
+	tw.Flush()
+}
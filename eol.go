@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	goversion "go/version"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// indexCacheFile is the stable releases of the last fetched index. It is
+// read to check the support of the selected version without network.
+const indexCacheFile = "index.json"
+
+// eolWarnInterval is how often the same version is warned about by Run.
+const eolWarnInterval = 24 * time.Hour
+
+// Values of the eol config, which selects what happens when the selected
+// version is unsupported or has a newer patch release.
+const (
+	eolOff  = "off"
+	eolWarn = "warn"
+	eolFail = "fail"
+)
+
+type indexCache struct {
+	FetchedAt time.Time    `json:"fetched_at"`
+	Versions  []*GoVersion `json:"versions"`
+}
+
+// writeIndexCache records the stable releases of versions, without files.
+func writeIndexCache(ctx context.Context, baseDir string, versions []*GoVersion) {
+	cache := indexCache{FetchedAt: time.Now()}
+	for _, v := range versions {
+		if v.Stable {
+			cache.Versions = append(cache.Versions, &GoVersion{Version: v.Version, Stable: v.Stable})
+		}
+	}
+	b, err := json.Marshal(cache)
+	if err != nil {
+		debugf(ctx, "encode index cache: %v", err)
+		return
+	}
+	path := filepath.Join(baseDir, cacheDir, indexCacheFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		debugf(ctx, "create cache dir: %v", err)
+		return
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		debugf(ctx, "write index cache: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		debugf(ctx, "write index cache: %v", err)
+	}
+}
+
+func readIndexCache(baseDir string) (*indexCache, error) {
+	b, err := os.ReadFile(filepath.Join(baseDir, cacheDir, indexCacheFile))
+	if err != nil {
+		return nil, err
+	}
+	var cache indexCache
+	if err := json.Unmarshal(b, &cache); err != nil {
+		return nil, err
+	}
+	return &cache, nil
+}
+
+// supportProblem returns why v (e.g. go1.21.5) should be upgraded according
+// to the cached index, or "" if it is supported and up to date or the index
+// is not cached.
+func supportProblem(baseDir, v string) string {
+	if !goversion.IsValid(v) {
+		return ""
+	}
+	cache, err := readIndexCache(baseDir)
+	if err != nil {
+		return ""
+	}
+	patches := latestPatches(cache.Versions)
+	latestPatch, outdated, unsupported := supportStatus(v, patches)
+	switch {
+	case unsupported:
+		return fmt.Sprintf("%s is no longer supported by the Go project. The latest release is %s", v, patches[0])
+	case outdated:
+		return fmt.Sprintf("%s is missing security fixes of %s. Run `gvs upgrade %s`", v, latestPatch, goversion.Lang(v)[2:])
+	}
+	return ""
+}
+
+// checkSupport reports the problem of versionDir by the eol config. Warnings
+// are printed once per eolWarnInterval for each version unless always is
+// true. It returns an error if the config is fail.
+//
+// The verdict is recorded in cache/eol/<version> until index.json changes,
+// so that a supported version costs two stats and no config or index read.
+func checkSupport(ctx context.Context, baseDir, versionDir string, always bool) error {
+	index, err := os.Stat(filepath.Join(baseDir, cacheDir, indexCacheFile))
+	if err != nil {
+		return nil
+	}
+	name := filepath.Base(versionDir)
+	stamp := filepath.Join(baseDir, cacheDir, "eol", name)
+
+	var problem string
+	var warnedAt time.Time
+	fresh := true
+	if info, err := os.Stat(stamp); err == nil && info.ModTime().After(index.ModTime()) {
+		b, err := os.ReadFile(stamp)
+		if err == nil {
+			problem, warnedAt, fresh = string(b), info.ModTime(), false
+		}
+	}
+	if !fresh && problem == "" {
+		return nil
+	}
+
+	mode, err := eolMode(baseDir)
+	if err != nil || mode == eolOff {
+		return err
+	}
+	if fresh {
+		problem = supportProblem(baseDir, name)
+		writeEOLStamp(ctx, stamp, problem)
+	}
+	if problem == "" {
+		return nil
+	}
+	if mode == eolFail {
+		return fmt.Errorf("%s", problem)
+	}
+	if !fresh && !always && time.Since(warnedAt) < eolWarnInterval {
+		return nil
+	}
+
+	warnf(ctx, "warning: %s", problem)
+	if !fresh && !always {
+		writeEOLStamp(ctx, stamp, problem)
+	}
+	return nil
+}
+
+func eolMode(baseDir string) (string, error) {
+	c, err := loadConfig(baseDir)
+	if err != nil {
+		return "", err
+	}
+	switch mode := c.get("eol"); mode {
+	case "":
+		return eolWarn, nil
+	case eolOff, eolWarn, eolFail:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("eol: unknown value %q", mode)
+	}
+}
+
+// writeEOLStamp records the verdict of a version. Its modification time is
+// when the version was warned about last.
+func writeEOLStamp(ctx context.Context, path, problem string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		debugf(ctx, "create eol dir: %v", err)
+		return
+	}
+	now := time.Now()
+	if err := os.WriteFile(path, []byte(problem), 0o644); err != nil {
+		debugf(ctx, "write eol stamp: %v", err)
+	} else if err := os.Chtimes(path, now, now); err != nil {
+		debugf(ctx, "write eol stamp: %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestSupportStatus(t *testing.T) {
+	patches := []string{"go1.23.2", "go1.22.8", "go1.21.13"}
+	tests := []struct {
+		version         string
+		wantLatestPatch string
+		wantOutdated    bool
+		wantUnsupported bool
+	}{
+		{"go1.23.2", "go1.23.2", false, false},
+		{"go1.23.0", "go1.23.2", true, false},
+		{"go1.22.8", "go1.22.8", false, false},
+		{"go1.22.1", "go1.22.8", true, false},
+		{"go1.22", "go1.22.8", false, false},
+		{"go1.21.13", "go1.21.13", false, true},
+		{"go1.20.1", "", false, true},
+		{"go1.24rc1", "", false, false},
+		{"latest", "", false, false},
+	}
+	for _, tt := range tests {
+		latestPatch, outdated, unsupported := supportStatus(tt.version, patches)
+		if latestPatch != tt.wantLatestPatch || outdated != tt.wantOutdated || unsupported != tt.wantUnsupported {
+			t.Errorf("supportStatus(%s) = %q, %v, %v, want %q, %v, %v", tt.version,
+				latestPatch, outdated, unsupported, tt.wantLatestPatch, tt.wantOutdated, tt.wantUnsupported)
+		}
+	}
+}
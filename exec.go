@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var execVersionArg string
+
+var ExecCmd = &cobra.Command{
+	Use:   "exec [--version X] -- command [args...]",
+	Short: "Run any command in the environment of the selected version",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		verbose = false
+		exitOnError(cmd.Context(), Exec(cmd.Context(), execVersionArg, args[0], args[1:]))
+	},
+}
+
+func init() {
+	ExecCmd.Flags().StringVar(&execVersionArg, "version", autoVersion, "specify running version")
+	ExecCmd.Flags().SetInterspersed(false)
+}
+
+// Exec runs command found in PATH with GOROOT and PATH set up for the
+// version, so that go called by the command is the version.
+func Exec(ctx context.Context, versionStr, command string, args []string) error {
+	baseDir, err := checkInit()
+	if err != nil {
+		return err
+	}
+	versionDir, err := installedVersionDir(ctx, baseDir, versionStr, ".", "")
+	if err != nil {
+		return err
+	}
+
+	env := toolchainEnviron(os.Environ(), versionDir)
+	path, err := lookPathIn(command, env)
+	if err != nil {
+		return err
+	}
+	return execCommand(path, args, env)
+}
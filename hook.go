@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	envShellArg string
+	hookEnvArg  bool
+)
+
+var HookCmd = &cobra.Command{
+	Use:       "hook [bash|zsh|fish]",
+	Short:     "Print the shell hook switching PATH and GOROOT on cd",
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: shells,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := Hook(cmd.Context(), args[0]); err != nil {
+			fatal(cmd.Context(), err)
+		}
+	},
+}
+
+var EnvCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Print the shell code setting PATH and GOROOT for the current directory",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		if err := Env(cmd.Context(), envShellArg); err != nil {
+			fatal(cmd.Context(), err)
+		}
+	},
+}
+
+func init() {
+	HookCmd.Flags().BoolVar(&hookEnvArg, "env", true, "switch PATH and GOROOT on cd. false only defines the gvs function for gvs shell")
+	EnvCmd.Flags().StringVar(&envShellArg, "shell", "bash", "shell of the output(bash, zsh or fish)")
+}
+
+var shells = []string{"bash", "zsh", "fish"}
+
+const (
+	// envActiveKey holds the version directory activated by the hook.
+	envActiveKey = "__GVS_DIR"
+	// envSavedPrefix prefixes the values the hook overwrote.
+	envSavedPrefix = "__GVS_OLD_"
+	// envHookKey is exported by the hook so that gvs doctor can tell it is
+	// loaded outside projects.
+	envHookKey = "__GVS_HOOK"
+)
+
+// hookKeys are the variables the hook changes. GOTOOLCHAIN is left to the
+// user in the interactive shell.
+var hookKeys = []string{"GOROOT", "PATH"}
+
+func Hook(_ context.Context, shell string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	gvs := quoteShell(shell, executable)
+
+	var function, hook string
+	switch shell {
+	case "bash", "zsh":
+		function = fmt.Sprintf(`gvs() {
+  if [ "$1" = shell ]; then
+    shift
+    eval "$(%[1]s shell --shell %[2]s "$@")"
+  else
+    %[1]s "$@"
+  fi
+}
+`, gvs, shell)
+	case "fish":
+		function = fmt.Sprintf(`function gvs
+  if test "$argv[1]" = shell
+    %[1]s shell --shell fish $argv[2..-1] | source
+  else
+    %[1]s $argv
+  end
+end
+`, gvs)
+	default:
+		return fmt.Errorf("%s is not supported shell", shell)
+	}
+
+	switch shell {
+	case "bash":
+		hook = fmt.Sprintf(`_gvs_hook() {
+  local previous_exit_status=$?
+  eval "$(%[1]s env --shell bash)"
+  return $previous_exit_status
+}
+if [[ ";${PROMPT_COMMAND[*]:-};" != *";_gvs_hook;"* ]]; then
+  PROMPT_COMMAND="_gvs_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+fi
+export %[2]s=1
+`, gvs, envHookKey)
+	case "zsh":
+		hook = fmt.Sprintf(`_gvs_hook() {
+  eval "$(%[1]s env --shell zsh)"
+}
+typeset -ag precmd_functions chpwd_functions
+if (( ! ${precmd_functions[(I)_gvs_hook]} )); then
+  precmd_functions=(_gvs_hook $precmd_functions)
+fi
+if (( ! ${chpwd_functions[(I)_gvs_hook]} )); then
+  chpwd_functions=(_gvs_hook $chpwd_functions)
+fi
+export %[2]s=1
+`, gvs, envHookKey)
+	case "fish":
+		hook = fmt.Sprintf(`function __gvs_hook --on-variable PWD --on-event fish_prompt
+  %[1]s env --shell fish | source
+end
+set -gx %[2]s 1
+`, gvs, envHookKey)
+	}
+
+	script := function
+	if hookEnvArg {
+		script += hook
+	}
+	_, err = os.Stdout.WriteString(script)
+	return err
+}
+
+// Env prints the shell code which activates the version of the current
+// directory, or restores the environment when the version does not come
+// from a project file.
+func Env(ctx context.Context, shell string) error {
+	if !slices.Contains(shells, shell) {
+		return fmt.Errorf("%s is not supported shell", shell)
+	}
+	baseDir, err := checkInit()
+	if err != nil {
+		return err
+	}
+
+	current := environMap(os.Environ())
+	target := restoreEnviron(current)
+
+	candidate, err := decideCandidate(ctx, baseDir, ".", "")
+	if err != nil && !errors.Is(err, ErrNotFoundGlobalVersion) {
+		return err
+	}
+	if candidate != nil && candidate.Source != sourceGlobal {
+		parsedVersion, err := parseVersionString(candidate.Version)
+		if err != nil {
+			return err
+		}
+		name, err := findLocalVersion(baseDir, parsedVersion)
+		if err != nil {
+			if !errors.Is(err, ErrNotFoundLocalVersion) {
+				return err
+			}
+			warnf(ctx, "%s is not installed. Run `gvs download %s`", candidate.Version, candidate.Version)
+		} else {
+			target = activateEnviron(target, filepath.Join(baseDir, "versions", name))
+		}
+	}
+
+	var buf strings.Builder
+	keys := append(slices.Clone(hookKeys), envActiveKey)
+	for _, key := range hookKeys {
+		keys = append(keys, envSavedPrefix+key)
+	}
+	for _, key := range keys {
+		value, ok := target[key]
+		old, had := current[key]
+		switch {
+		case ok && (!had || old != value):
+			buf.WriteString(exportShell(shell, key, value))
+		case !ok && had:
+			buf.WriteString(unsetShell(shell, key))
+		}
+	}
+	_, err = os.Stdout.WriteString(buf.String())
+	return err
+}
+
+func environMap(environ []string) map[string]string {
+	m := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if key, value, ok := strings.Cut(kv, "="); ok {
+			m[key] = value
+		}
+	}
+	return m
+}
+
+func environList(m map[string]string) []string {
+	environ := make([]string, 0, len(m))
+	for key, value := range m {
+		environ = append(environ, key+"="+value)
+	}
+	slices.Sort(environ)
+	return environ
+}
+
+// restoreEnviron reverts the changes made by activateEnviron. Variables
+// the user changed after the activation are kept.
+func restoreEnviron(current map[string]string) map[string]string {
+	env := make(map[string]string, len(current))
+	for key, value := range current {
+		env[key] = value
+	}
+	dir, ok := env[envActiveKey]
+	if !ok {
+		return env
+	}
+	delete(env, envActiveKey)
+
+	if env["GOROOT"] == dir {
+		if old, ok := env[envSavedPrefix+"GOROOT"]; ok {
+			env["GOROOT"] = old
+		} else {
+			delete(env, "GOROOT")
+		}
+	}
+	delete(env, envSavedPrefix+"GOROOT")
+	binDirs := versionBinDirs(dir)
+	var paths []string
+	for _, path := range filepath.SplitList(env["PATH"]) {
+		if !slices.Contains(binDirs, path) {
+			paths = append(paths, path)
+		}
+	}
+	env["PATH"] = strings.Join(paths, string(filepath.ListSeparator))
+	return env
+}
+
+// activateEnviron sets GOROOT and PATH of base for versionDir like commands
+// run by gvs, and saves the GOROOT it overwrites so that restoreEnviron can
+// revert it.
+func activateEnviron(base map[string]string, versionDir string) map[string]string {
+	env := environMap(toolchainEnviron(environList(base), versionDir))
+	if old, ok := base["GOTOOLCHAIN"]; ok {
+		env["GOTOOLCHAIN"] = old
+	} else {
+		delete(env, "GOTOOLCHAIN")
+	}
+	if old, ok := base["GOROOT"]; ok {
+		env[envSavedPrefix+"GOROOT"] = old
+	}
+	env[envActiveKey] = versionDir
+	return env
+}
+
+func quoteShell(shell, value string) string {
+	if shell == "fish" {
+		return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func exportShell(shell, key, value string) string {
+	if shell != "fish" {
+		return fmt.Sprintf("export %s=%s;\n", key, quoteShell(shell, value))
+	}
+	if key == "PATH" {
+		var paths []string
+		for _, path := range filepath.SplitList(value) {
+			paths = append(paths, quoteShell(shell, path))
+		}
+		return fmt.Sprintf("set -gx PATH %s;\n", strings.Join(paths, " "))
+	}
+	return fmt.Sprintf("set -gx %s %s;\n", key, quoteShell(shell, value))
+}
+
+func unsetShell(shell, key string) string {
+	if shell == "fish" {
+		return fmt.Sprintf("set -e %s;\n", key)
+	}
+	return fmt.Sprintf("unset %s;\n", key)
+}
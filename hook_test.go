@@ -0,0 +1,70 @@
+package main
+
+import (
+	"maps"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestActivateEnviron(t *testing.T) {
+	versionDir := filepath.Join(t.TempDir(), "go1.22.1")
+	path := strings.Join([]string{"/usr/local/bin", "/usr/bin"}, string(filepath.ListSeparator))
+	activePath := strings.Join(append(versionBinDirs(versionDir), "/usr/local/bin", "/usr/bin"), string(filepath.ListSeparator))
+
+	tests := []struct {
+		name string
+		base map[string]string
+		// change is applied in the project before leaving it.
+		change       map[string]string
+		wantActive   map[string]string
+		wantRestored map[string]string
+	}{
+		{
+			name:         "nothing set",
+			base:         map[string]string{"PATH": path},
+			wantActive:   map[string]string{"PATH": activePath, "GOROOT": versionDir, envActiveKey: versionDir},
+			wantRestored: map[string]string{"PATH": path},
+		},
+		{
+			name: "user values",
+			base: map[string]string{"PATH": path, "GOROOT": "/usr/local/go", "GOTOOLCHAIN": "auto"},
+			wantActive: map[string]string{
+				"PATH": activePath, "GOROOT": versionDir, "GOTOOLCHAIN": "auto",
+				envSavedPrefix + "GOROOT": "/usr/local/go", envActiveKey: versionDir,
+			},
+			wantRestored: map[string]string{"PATH": path, "GOROOT": "/usr/local/go", "GOTOOLCHAIN": "auto"},
+		},
+		{
+			name:   "GOTOOLCHAIN set in the project",
+			base:   map[string]string{"PATH": path},
+			change: map[string]string{"GOTOOLCHAIN": "go1.23.0"},
+			wantActive: map[string]string{
+				"PATH": activePath, "GOROOT": versionDir, envActiveKey: versionDir,
+			},
+			wantRestored: map[string]string{"PATH": path, "GOTOOLCHAIN": "go1.23.0"},
+		},
+		{
+			name:   "GOROOT changed in the project",
+			base:   map[string]string{"PATH": path, "GOROOT": "/usr/local/go"},
+			change: map[string]string{"GOROOT": "/opt/go"},
+			wantActive: map[string]string{
+				"PATH": activePath, "GOROOT": versionDir,
+				envSavedPrefix + "GOROOT": "/usr/local/go", envActiveKey: versionDir,
+			},
+			wantRestored: map[string]string{"PATH": path, "GOROOT": "/opt/go"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			active := activateEnviron(tt.base, versionDir)
+			if !maps.Equal(active, tt.wantActive) {
+				t.Errorf("activateEnviron() = %v, want %v", active, tt.wantActive)
+			}
+			maps.Copy(active, tt.change)
+			if restored := restoreEnviron(active); !maps.Equal(restored, tt.wantRestored) {
+				t.Errorf("restoreEnviron() = %v, want %v", restored, tt.wantRestored)
+			}
+		})
+	}
+}
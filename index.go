@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	goversion "go/version"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+func fetchRemoteVersions(ctx context.Context) ([]*GoVersion, error) {
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, goVersionURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("status is %d. response %s", resp.StatusCode, body)
+	}
+
+	var versions []*GoVersion
+	if err := json.NewDecoder(resp.Body).Decode(&versions); err != nil {
+		return nil, fmt.Errorf("decode response body: %w", err)
+	}
+	if baseDir, err := checkInit(); err == nil {
+		writeIndexCache(ctx, baseDir, versions)
+	}
+	return versions, nil
+}
+
+// stableReleases returns the stable releases newest first.
+func stableReleases(versions []*GoVersion) []string {
+	var releases []string
+	for _, v := range versions {
+		if v.Stable && goversion.IsValid(v.Version) {
+			releases = append(releases, v.Version)
+		}
+	}
+	slices.SortFunc(releases, func(l, r string) int { return goversion.Compare(r, l) })
+	return releases
+}
+
+// latestPatches returns the latest stable patch release of each minor
+// version, newest first.
+func latestPatches(versions []*GoVersion) []string {
+	var patches []string
+	for _, release := range stableReleases(versions) {
+		if len(patches) == 0 || goversion.Lang(patches[len(patches)-1]) != goversion.Lang(release) {
+			patches = append(patches, release)
+		}
+	}
+	return patches
+}
+
+// supportStatus compares v (e.g. go1.21.5) with patches given by
+// latestPatches. latestPatch is the latest patch release of the minor
+// version of v, or "" if there is none. v is outdated if it is older than
+// latestPatch; a minor version like go1.21 always means the latest patch.
+// v is unsupported if it is older than the two latest minor versions, which
+// the Go project supports.
+func supportStatus(v string, patches []string) (latestPatch string, outdated, unsupported bool) {
+	if !goversion.IsValid(v) || len(patches) == 0 {
+		return "", false, false
+	}
+	lang := goversion.Lang(v)
+	for _, patch := range patches {
+		if goversion.Lang(patch) == lang {
+			latestPatch = patch
+			outdated = v != lang && goversion.Compare(v, patch) < 0
+			break
+		}
+	}
+	oldestSupported := patches[min(1, len(patches)-1)]
+	unsupported = goversion.Compare(lang, goversion.Lang(oldestSupported)) < 0
+	return latestPatch, outdated, unsupported
+}
+
+// resolveAlias returns the version of an alias(stable or oldstable), or
+// alias itself if it is not an alias.
+func resolveAlias(ctx context.Context, alias string) (string, error) {
+	var index int
+	switch alias {
+	case "stable":
+		index = 0
+	case "oldstable":
+		index = 1
+	default:
+		return alias, nil
+	}
+	versions, err := fetchRemoteVersions(ctx)
+	if err != nil {
+		return "", err
+	}
+	patches := latestPatches(versions)
+	if len(patches) <= index {
+		return "", fmt.Errorf("%s is not found", alias)
+	}
+	return strings.TrimPrefix(patches[index], "go"), nil
+}
@@ -1,33 +1,81 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	initScriptArg    bool
+	initShellArg     string
+	initWriteArg     bool
+	initVersionArg   string
+	initUninstallArg bool
+)
+
 var InitCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize gvs",
 	Args:  cobra.NoArgs,
+	Long: `Initialize gvs.
+With --shell (or --write, which detects the shell from $SHELL), gvs init sets
+up the shell: a block calling gvs hook is written to the rc file of the shell
+and the completion script is installed. Without --write they are printed.
+--uninstall removes them.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := Initialize(); err != nil {
-			fatal(cmd.Context(), err)
+		ctx := cmd.Context()
+		if initUninstallArg {
+			if err := UninstallShell(ctx); err != nil {
+				fatal(ctx, err)
+			}
+			return
+		}
+		if err := Initialize(ctx); err != nil {
+			fatal(ctx, err)
+		}
+		if initVersionArg != "" {
+			if err := Use(ctx, initVersionArg); err != nil {
+				fatal(ctx, err)
+			}
 		}
-		fmt.Printf(`Initialize Success.
+		if initShellArg == "" && !initWriteArg {
+			fmt.Printf(`Initialize Success.
 Add gvs to PATH
 
 export PATH="$HOME/.gvs/bin:$PATH"
 
+Or, set up the shell
+
+gvs init --shell bash --write
+
 And, select global Go version
 
 gvs use 1.22
 `)
+			return
+		}
+		if err := SetupShell(ctx, cmd.Root(), initShellArg, initWriteArg); err != nil {
+			fatal(ctx, err)
+		}
 	},
 }
 
+func init() {
+	InitCmd.Flags().BoolVar(&initScriptArg, "script", false, "create bash scripts calling gvs instead of links to gvs")
+	InitCmd.Flags().StringVar(&initShellArg, "shell", "", "shell to set up(bash, zsh or fish). default is detected from $SHELL")
+	InitCmd.Flags().BoolVar(&initWriteArg, "write", false, "write the rc file and the completion script instead of printing them")
+	InitCmd.Flags().StringVar(&initVersionArg, "version", "", "set the global version")
+	InitCmd.Flags().BoolVar(&initUninstallArg, "uninstall", false, "remove the blocks from the rc files and the completion scripts")
+	InitCmd.MarkFlagsMutuallyExclusive("uninstall", "write")
+}
+
 const gvsDir = ".gvs"
 
 func checkInit() (dir string, err error) {
@@ -43,7 +91,7 @@ func checkInit() (dir string, err error) {
 	return dir, nil
 }
 
-func Initialize() error {
+func Initialize(ctx context.Context) error {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return err
@@ -66,18 +114,260 @@ func Initialize() error {
 		}
 	}
 
-	if err := createScript(dir, "go"); err != nil {
-		return fmt.Errorf("create go script: %w", err)
+	return rehash(ctx, dir, initScriptArg)
+}
+
+// createShim links the command to the gvs binary, which dispatches on the
+// name it is called by. It falls back to a hard link and then to a script.
+// The shim is created under a temporary name and renamed over the old one,
+// so that concurrent calls never find the shim missing.
+func createShim(dir, command string, script bool) error {
+	path := filepath.Join(dir, "bin", command)
+	tmp := filepath.Join(dir, "bin", "."+command+".tmp")
+	if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := newShim(tmp, command, script); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func newShim(path, command string, script bool) error {
+	if script {
+		return createScript(path, command)
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return createScript(path, command)
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+
+	if err := os.Symlink(executable, path); err == nil {
+		return nil
+	}
+	if err := os.Link(executable, path); err == nil {
+		return nil
+	}
+	return createScript(path, command)
+}
+
+func createScript(path, command string) error {
+	if err := os.WriteFile(path, []byte(scriptShimPrefix+command+" -- \"$@\"\n"), 0744); err != nil {
+		return err
+	}
+	return nil
+}
+
+const (
+	rcBlockBegin = "# >>> gvs initialize >>>"
+	rcBlockEnd   = "# <<< gvs initialize <<<"
+)
+
+// SetupShell writes the rc block and the completion script of the shell, or
+// prints them if write is false. An empty shell is detected from $SHELL.
+func SetupShell(ctx context.Context, root *cobra.Command, shell string, write bool) error {
+	if shell == "" {
+		shell = filepath.Base(os.Getenv("SHELL"))
+		if !slices.Contains(shells, shell) {
+			return fmt.Errorf("cannot detect the shell from SHELL=%q. Specify --shell", os.Getenv("SHELL"))
+		}
+	} else if !slices.Contains(shells, shell) {
+		return fmt.Errorf("%s is not supported shell", shell)
+	}
+	baseDir, err := checkInit()
+	if err != nil {
+		return err
+	}
+	rc, completion, err := shellFiles(baseDir, shell)
+	if err != nil {
+		return err
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	block := rcBlock(shell, executable, completion)
+
+	var script bytes.Buffer
+	switch shell {
+	case "bash":
+		err = root.GenBashCompletionV2(&script, true)
+	case "zsh":
+		err = root.GenZshCompletion(&script)
+	case "fish":
+		err = root.GenFishCompletion(&script, true)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !write {
+		fmt.Printf("Add the following to %s\n\n%s\nThe completion script is installed into %s with --write.\n", rc, block, completion)
+		return nil
 	}
-	if err := createScript(dir, "gofmt"); err != nil {
-		return fmt.Errorf("create go script: %w", err)
+	if err := writeRCBlock(rc, block); err != nil {
+		return err
 	}
+	infof(ctx, "write %s", rc)
+	if err := os.MkdirAll(filepath.Dir(completion), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(completion, script.Bytes(), 0644); err != nil {
+		return err
+	}
+	infof(ctx, "write %s", completion)
+	fmt.Printf("Restart the shell or run `source %s`\n", rc)
 	return nil
 }
 
-func createScript(dir, command string) error {
-	if err := os.WriteFile(filepath.Join(dir, "bin", command), []byte("#!/bin/bash\ngvs run "+command+" -- \"$@\"\n"), 0744); err != nil {
+// UninstallShell removes the rc blocks and the completion scripts of all
+// shells. Installed versions are left in $HOME/.gvs.
+func UninstallShell(ctx context.Context) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
 		return err
 	}
+	baseDir := filepath.Join(home, gvsDir)
+	for _, shell := range shells {
+		rc, completion, err := shellFiles(baseDir, shell)
+		if err != nil {
+			return err
+		}
+		removed, err := removeRCBlock(rc)
+		if err != nil {
+			return err
+		}
+		if removed {
+			infof(ctx, "remove the block from %s", rc)
+		}
+		if err := os.Remove(completion); err == nil {
+			infof(ctx, "remove %s", completion)
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+	infof(ctx, "remove %s to delete installed versions", baseDir)
 	return nil
 }
+
+// shellFiles returns the rc file and the completion script of the shell.
+func shellFiles(baseDir, shell string) (rc, completion string, err error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", "", err
+	}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(home, ".config")
+	}
+	switch shell {
+	case "bash":
+		dataHome := os.Getenv("XDG_DATA_HOME")
+		if dataHome == "" {
+			dataHome = filepath.Join(home, ".local", "share")
+		}
+		return filepath.Join(home, ".bashrc"), filepath.Join(dataHome, "bash-completion", "completions", "gvs"), nil
+	case "zsh":
+		zdotdir := os.Getenv("ZDOTDIR")
+		if zdotdir == "" {
+			zdotdir = home
+		}
+		return filepath.Join(zdotdir, ".zshrc"), filepath.Join(baseDir, "completions", "_gvs"), nil
+	case "fish":
+		return filepath.Join(configHome, "fish", "config.fish"), filepath.Join(configHome, "fish", "completions", "gvs.fish"), nil
+	default:
+		return "", "", fmt.Errorf("%s is not supported shell", shell)
+	}
+}
+
+// rcBlock returns the block put in the rc file. It puts the shims on PATH,
+// defines the gvs function for gvs shell and loads the completion script
+// where the shell does not load it by itself. The hook switching PATH is not
+// loaded because the shims already select the version.
+func rcBlock(shell, executable, completion string) string {
+	gvs := quoteShell(shell, executable)
+	var body string
+	switch shell {
+	case "bash":
+		body = fmt.Sprintf("export PATH=\"$HOME/%s/bin:$PATH\"\neval \"$(%s hook bash --env=false)\"\ncomplete -p gvs >/dev/null 2>&1 || [ ! -f %[3]s ] || . %[3]s\n",
+			gvsDir, gvs, quoteShell(shell, completion))
+	case "zsh":
+		body = fmt.Sprintf("export PATH=\"$HOME/%s/bin:$PATH\"\neval \"$(%s hook zsh --env=false)\"\nfpath=(%s $fpath)\n(( $+functions[compdef] )) && autoload -Uz _gvs && compdef _gvs gvs\n",
+			gvsDir, gvs, quoteShell(shell, filepath.Dir(completion)))
+	case "fish":
+		body = fmt.Sprintf("set -gx PATH $HOME/%s/bin $PATH\n%s hook fish --env=false | source\n", gvsDir, gvs)
+	}
+	return rcBlockBegin + "\n" + body + rcBlockEnd + "\n"
+}
+
+// writeRCBlock replaces the block in the rc file, or appends it.
+func writeRCBlock(path, block string) error {
+	b, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	content := string(b)
+	if begin, end, ok := findRCBlock(content); ok {
+		content = content[:begin] + block + content[end:]
+	} else {
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		if content != "" {
+			content += "\n"
+		}
+		content += block
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+// removeRCBlock removes the block from the rc file and reports whether the
+// block was found.
+func removeRCBlock(path string) (bool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	content := string(b)
+	begin, end, ok := findRCBlock(content)
+	if !ok {
+		return false, nil
+	}
+	// drop the blank line writeRCBlock put before the block.
+	before := content[:begin]
+	if strings.HasSuffix(before, "\n\n") {
+		before = before[:len(before)-1]
+	}
+	return true, os.WriteFile(path, []byte(before+content[end:]), 0644)
+}
+
+// findRCBlock returns the range of the block including its last newline.
+func findRCBlock(content string) (begin, end int, ok bool) {
+	begin = strings.Index(content, rcBlockBegin)
+	if begin < 0 {
+		return 0, 0, false
+	}
+	i := strings.Index(content[begin:], rcBlockEnd)
+	if i < 0 {
+		return 0, 0, false
+	}
+	end = begin + i + len(rcBlockEnd)
+	if end < len(content) && content[end] == '\n' {
+		end++
+	}
+	return begin, end, true
+}
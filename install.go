@@ -5,20 +5,30 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"slices"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	installVersionArg string
+	installLocalArg   bool
+)
+
 var InstallCmd = &cobra.Command{
 	Use:   "install",
-	Short: "install tools by global Go version",
-	Args:  cobra.MinimumNArgs(1),
+	Short: "install tools by Go version",
+	Long: `Install tools by Go version into the gvs-bin directory of the version.
+The global version is used by default.`,
+	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx := cmd.Context()
-		if err := Install(ctx, args); err != nil {
+		versionStr := installVersionArg
+		if installLocalArg {
+			versionStr = autoVersion
+		}
+		if err := Install(ctx, versionStr, args); err != nil {
 			if errors.Is(err, ErrNotFoundGlobalVersion) {
 				fatal(ctx, fmt.Errorf("no specify version. Run `nvs use`"))
 			}
@@ -27,39 +37,59 @@ var InstallCmd = &cobra.Command{
 	},
 }
 
-func Install(ctx context.Context, args []string) error {
+func init() {
+	InstallCmd.Flags().StringVar(&installVersionArg, "version", "", "install by the version")
+	InstallCmd.Flags().BoolVar(&installLocalArg, "local", false, "install by the version of the current directory")
+	InstallCmd.MarkFlagsMutuallyExclusive("version", "local")
+}
+
+// Install runs go install with the version and GOBIN set to the gvs-bin
+// directory of the version. An empty versionStr means the global version.
+func Install(ctx context.Context, versionStr string, args []string) error {
+	return installInto(ctx, versionStr, "", args)
+}
+
+// installInto is Install with GOBIN. An empty gobin means gvs-bin.
+func installInto(ctx context.Context, versionStr, gobin string, args []string) error {
 	baseDir, err := checkInit()
 	if err != nil {
 		return err
 	}
-	v, err := os.ReadFile(filepath.Join(baseDir, globalVersionFile))
-	if err != nil {
-		if os.IsNotExist(err) {
-			return ErrNotFoundGlobalVersion
+	if versionStr == "" {
+		versionStr, err = readGlobalVersion(baseDir)
+		if err != nil {
+			return err
 		}
-		return err
 	}
-	parsedVersion, err := parseVersionString(string(v))
+	versionDir, err := installedVersionDir(ctx, baseDir, versionStr, ".", "")
 	if err != nil {
 		return err
 	}
-	goBasePath, err := findLocalVersion(baseDir, parsedVersion)
-	if err != nil {
-		return err
+	if gobin == "" {
+		gobin = filepath.Join(versionDir, toolBinDir)
 	}
 
-	commandArgs := slices.Concat([]string{"install"}, args)
-	infof(ctx, "use %s", goBasePath)
-	cmd := exec.CommandContext(ctx, filepath.Join(baseDir, "versions", goBasePath, "bin", "go"), commandArgs...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Start(); err != nil {
+	if err := goInstall(ctx, versionDir, gobin, args); err != nil {
 		return err
 	}
-	if err := cmd.Wait(); err != nil {
-		return err
+	return Rehash(ctx)
+}
+
+func readGlobalVersion(baseDir string) (string, error) {
+	candidate, err := globalSource{baseDir: baseDir}.lookup("")
+	if err != nil {
+		return "", err
+	}
+	if candidate == nil || candidate.Version == "" {
+		return "", ErrNotFoundGlobalVersion
 	}
-	return nil
+	return candidate.Version, nil
+}
+
+// goInstall runs go install of versionDir with GOBIN.
+func goInstall(ctx context.Context, versionDir, gobin string, args []string) error {
+	commandArgs := slices.Concat([]string{"install"}, args)
+	infof(ctx, "use %s", filepath.Base(versionDir))
+	env := setEnviron(toolchainEnviron(os.Environ(), versionDir), "GOBIN", gobin)
+	return runCommand(filepath.Join(versionDir, "bin", "go"), commandArgs, env)
 }
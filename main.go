@@ -13,6 +13,14 @@ func main() {
 	ctx = context.WithValue(ctx, loggerOutKey{}, log.New(os.Stdout, "[gvs] ", 0))
 	ctx = context.WithValue(ctx, loggerErrKey{}, log.New(os.Stderr, "[gvs] ", 0))
 
+	if name := shimName(os.Args[0]); calledAsShim(name) {
+		runAndExit(ctx, autoVersion, name, os.Args[1:])
+		return
+	}
+	if runShim(ctx, os.Args[1:]) {
+		return
+	}
+
 	rootCmd := &cobra.Command{Use: "gvs"}
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "output debug log")
 
@@ -22,5 +30,22 @@ func main() {
 	rootCmd.AddCommand(UseCmd)
 	rootCmd.AddCommand(VersionsCmd)
 	rootCmd.AddCommand(InstallCmd)
+	rootCmd.AddCommand(CurrentCmd)
+	rootCmd.AddCommand(WhichCmd)
+	rootCmd.AddCommand(RehashCmd)
+	rootCmd.AddCommand(ExecCmd)
+	rootCmd.AddCommand(HookCmd)
+	rootCmd.AddCommand(EnvCmd)
+	rootCmd.AddCommand(ShellCmd)
+	rootCmd.AddCommand(MatrixCmd)
+	rootCmd.AddCommand(BisectCmd)
+	rootCmd.AddCommand(BenchCmd)
+	rootCmd.AddCommand(EachCmd)
+	rootCmd.AddCommand(ToolsCmd)
+	rootCmd.AddCommand(UninstallCmd)
+	rootCmd.AddCommand(PruneCmd)
+	rootCmd.AddCommand(UpgradeCmd)
+	rootCmd.AddCommand(OutdatedCmd)
+	rootCmd.AddCommand(DoctorCmd)
 	rootCmd.ExecuteContext(ctx)
 }
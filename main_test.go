@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMain runs main instead of the tests when TEST_GVS_MAIN is set, so that
+// the test binary can be executed as gvs.
+func TestMain(m *testing.M) {
+	if os.Getenv("TEST_GVS_MAIN") != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func testContext() context.Context {
+	ctx := context.WithValue(context.Background(), loggerOutKey{}, log.New(io.Discard, "", 0))
+	return context.WithValue(ctx, loggerErrKey{}, log.New(io.Discard, "", 0))
+}
+
+// setupHome creates $HOME/.gvs with fake versions whose go prints the version, and
+// a project directory where the upward search stops. It returns the gvs
+// directory and the project directory.
+func setupHome(t testing.TB, versions ...string) (baseDir, project string) {
+	t.Helper()
+	root := t.TempDir()
+	home := filepath.Join(root, "home")
+	project = filepath.Join(root, "project")
+	baseDir = filepath.Join(home, gvsDir)
+
+	t.Setenv("HOME", home)
+	t.Setenv("GVS_CEILING_DIRECTORIES", root)
+	t.Setenv(versionEnv, "")
+	os.Unsetenv(versionEnv)
+
+	for _, v := range versions {
+		bin := filepath.Join(baseDir, "versions", v, "bin")
+		writeFile(t, filepath.Join(bin, "go"), "#!/bin/sh\necho "+v+"\n")
+		if err := os.Chmod(filepath.Join(bin, "go"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, dir := range []string{filepath.Join(baseDir, "bin"), project} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return baseDir, project
+}
+
+// writeFile writes content with a modification time old enough to be cached.
+func writeFile(t testing.TB, path, content string) {
+	t.Helper()
+	writeFileAt(t, path, content, time.Now().Add(-time.Hour))
+}
+
+func writeFileAt(t testing.TB, path, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// runGVS runs the test binary as gvs in dir and returns its standard output.
+func runGVS(t testing.TB, dir string, env []string, args ...string) (string, error) {
+	t.Helper()
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(self, args...)
+	cmd.Dir = dir
+	cmd.Env = append(append(os.Environ(), "TEST_GVS_MAIN=1"), env...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return string(out), fmt.Errorf("%w: %s", err, stderr.String())
+	}
+	return string(out), nil
+}
+
+// setupWorkspace creates a workspace of nested modules with many
+// requirements like a real repository, and returns a deep directory in it.
+func setupWorkspace(t testing.TB, project string) string {
+	t.Helper()
+	var requires strings.Builder
+	for i := 0; i < 60; i++ {
+		fmt.Fprintf(&requires, "\tgithub.com/example/dependency%d v1.%d.0 // indirect\n", i, i)
+	}
+	writeFile(t, filepath.Join(project, "go.work"), "go 1.22.1\n\nuse (\n\t./services/api\n\t./libs/log\n)\n")
+	writeFile(t, filepath.Join(project, "libs", "log", "go.mod"), "module example.com/libs/log\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(project, "services", "api", "go.mod"),
+		"module example.com/services/api\n\ngo 1.22.0\n\ntoolchain go1.22.1\n\nrequire (\n"+requires.String()+")\n\nreplace example.com/libs/log => ../../libs/log\n")
+	dir := filepath.Join(project, "services", "api", "internal", "handler", "v2", "http", "middleware")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// BenchmarkShim compares running go through the shim fast path
+// (gvs run go -- args) with running it directly, in a deep directory of a
+// workspace.
+func BenchmarkShim(b *testing.B) {
+	baseDir, project := setupHome(b, "go1.22.1")
+	writeFile(b, filepath.Join(baseDir, globalVersionFile), "1.21")
+	project = setupWorkspace(b, project)
+	goBin := filepath.Join(baseDir, "versions", "go1.22.1", "bin", "go")
+	self, err := os.Executable()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	run := func(b *testing.B, cmd *exec.Cmd) {
+		cmd.Dir = project
+		if out, err := cmd.CombinedOutput(); err != nil {
+			b.Fatalf("%v: %s", err, out)
+		}
+	}
+
+	b.Run("direct", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			run(b, exec.Command(goBin, "version"))
+		}
+	})
+	b.Run("shim", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			cmd := exec.Command(self, "run", "go", "--", "version")
+			cmd.Env = append(os.Environ(), "TEST_GVS_MAIN=1")
+			run(b, cmd)
+		}
+	})
+	b.Run("resolve/cached", func(b *testing.B) {
+		ctx := testContext()
+		if _, err := decideCandidate(ctx, baseDir, project, ""); err != nil {
+			b.Fatal(err)
+		}
+		cachePath, err := resolveCachePath(baseDir, project, "")
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, ok := readResolveCache(ctx, cachePath); !ok {
+			b.Fatal("resolution is not cached")
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := decideCandidate(ctx, baseDir, project, ""); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("resolve/uncached", func(b *testing.B) {
+		b.Setenv("GVS_CACHE", "false")
+		ctx := testContext()
+		for i := 0; i < b.N; i++ {
+			if _, err := decideCandidate(ctx, baseDir, project, ""); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
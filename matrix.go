@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	matrixVersionsArg []string
+	matrixParallelArg int
+	matrixCaptureArg  bool
+)
+
+var MatrixCmd = &cobra.Command{
+	Use:   "matrix --versions 1.21,1.22,stable -- command [args...]",
+	Short: "Run a command with multiple versions in parallel",
+	Long: `Run a command with multiple versions in parallel.
+Versions can be a version, stable (the latest release), oldstable (the latest
+release of the previous minor version) or current (the version selected in the
+current directory). Missing versions are downloaded.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		jobs, err := Matrix(ctx, matrixVersionsArg, args[0], args[1:])
+		if err != nil {
+			fatal(ctx, err)
+		}
+		writeMatrixSummary(os.Stdout, jobs)
+		for _, job := range jobs {
+			if job.Err != nil {
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+func init() {
+	MatrixCmd.Flags().StringSliceVar(&matrixVersionsArg, "versions", nil, "versions to run (comma separated)")
+	MatrixCmd.Flags().IntVarP(&matrixParallelArg, "parallel", "p", runtime.NumCPU(), "number of versions run at once")
+	MatrixCmd.Flags().BoolVar(&matrixCaptureArg, "capture", false, "print the output of each version at once when it finishes instead of prefixing lines")
+	MatrixCmd.Flags().SetInterspersed(false)
+	MatrixCmd.MarkFlagRequired("versions")
+}
+
+// Matrix runs the command with each version in parallel. Each version has
+// its own build cache.
+func Matrix(ctx context.Context, versions []string, command string, args []string) ([]*parallelJob, error) {
+	baseDir, err := checkInit()
+	if err != nil {
+		return nil, err
+	}
+	versionDirs, err := matrixVersionDirs(ctx, baseDir, versions)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*parallelJob, len(versionDirs))
+	for i, versionDir := range versionDirs {
+		name := filepath.Base(versionDir)
+		env := pinnedEnviron(os.Environ(), versionDir)
+		env = setEnviron(env, "GOCACHE", filepath.Join(baseDir, cacheDir, "go-build", name))
+		jobs[i] = &parallelJob{Label: name, Version: name, Env: env}
+	}
+	runParallel(jobs, command, args, matrixParallelArg, matrixCaptureArg)
+	return jobs, nil
+}
+
+// matrixVersionDirs resolves aliases and installs the versions. Downloads
+// are done one by one before running anything.
+func matrixVersionDirs(ctx context.Context, baseDir string, versions []string) ([]string, error) {
+	var dirs []string
+	for _, v := range versions {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		var err error
+		if v == "current" {
+			v, err = decideVersion(ctx, baseDir, ".", "")
+		} else {
+			v, err = resolveAlias(ctx, v)
+		}
+		if err != nil {
+			return nil, err
+		}
+		dir, err := installedVersionDir(ctx, baseDir, v, "", "")
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("no versions are specified")
+	}
+	return dirs, nil
+}
+
+// parallelJob is a run of the command by runParallel.
+type parallelJob struct {
+	Label    string
+	Version  string
+	Dir      string
+	Env      []string
+	Err      error
+	Duration time.Duration
+}
+
+// runParallel runs the command for each job, at most parallel at once. The
+// output is prefixed with the label of the job line by line, or printed at
+// once when the job finishes if capture is true.
+func runParallel(jobs []*parallelJob, command string, args []string, parallel int, capture bool) {
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		sem = make(chan struct{}, max(parallel, 1))
+	)
+	for _, job := range jobs {
+		job := job
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			var (
+				captured  bytes.Buffer
+				outWriter = &prefixWriter{mu: &mu, w: os.Stdout, prefix: "[" + job.Label + "] "}
+				errWriter = &prefixWriter{mu: &mu, w: os.Stderr, prefix: "[" + job.Label + "] "}
+			)
+			var stdout, stderr io.Writer = outWriter, errWriter
+			if capture {
+				stdout, stderr = &captured, &captured
+			}
+
+			start := time.Now()
+			job.Err = runWithEnv(command, args, job.Env, job.Dir, stdout, stderr)
+			job.Duration = time.Since(start)
+
+			if capture {
+				mu.Lock()
+				fmt.Fprintf(os.Stdout, "=== %s\n", job.Label)
+				os.Stdout.Write(captured.Bytes())
+				mu.Unlock()
+			} else {
+				outWriter.Flush()
+				errWriter.Flush()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// runWithEnv runs the command found in the PATH of env in dir.
+func runWithEnv(command string, args, env []string, dir string, stdout, stderr io.Writer) error {
+	path, err := lookPathIn(command, env)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(path, args...)
+	cmd.Env = env
+	cmd.Dir = dir
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return startAndWait(cmd)
+}
+
+// setEnviron sets key to value in environ.
+func setEnviron(environ []string, key, value string) []string {
+	env := make([]string, 0, len(environ)+1)
+	for _, kv := range environ {
+		if !strings.HasPrefix(kv, key+"=") {
+			env = append(env, kv)
+		}
+	}
+	return append(env, key+"="+value)
+}
+
+func writeMatrixSummary(w io.Writer, jobs []*parallelJob) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nVERSION\tRESULT\tTIME")
+	for _, job := range jobs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", job.Version, resultString(job.Err), job.Duration.Round(time.Millisecond))
+	}
+	tw.Flush()
+}
+
+func resultString(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	var extErr *exec.ExitError
+	if errors.As(err, &extErr) {
+		return fmt.Sprintf("FAIL(%d)", exitCode(extErr))
+	}
+	return "FAIL(" + err.Error() + ")"
+}
+
+// prefixWriter writes each line with prefix. Lines of writers sharing mu
+// are not interleaved.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		p.mu.Lock()
+		_, err := fmt.Fprintf(p.w, "%s%s", p.prefix, p.buf[:i+1])
+		p.mu.Unlock()
+		if err != nil {
+			return len(b), err
+		}
+		p.buf = p.buf[i+1:]
+	}
+}
+
+// Flush writes the last line without a newline.
+func (p *prefixWriter) Flush() {
+	if len(p.buf) > 0 {
+		p.Write([]byte{'\n'})
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var outdatedJSONArg bool
+
+var OutdatedCmd = &cobra.Command{
+	Use:   "outdated [paths...]",
+	Short: "Report version pins which have newer releases",
+	Long: `Report version pins in .go-version, go.mod and go.work under the paths
+(default: the current directory) with the newest patch release of the pinned
+minor version, the newest stable release and whether the pinned minor version
+is still supported. Only the two newest minor versions are supported.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+		pins, err := Outdated(ctx, args)
+		if err != nil {
+			fatal(ctx, err)
+		}
+		if outdatedJSONArg {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(pins)
+		} else {
+			err = writeOutdated(os.Stdout, pins)
+		}
+		if err != nil {
+			fatal(ctx, err)
+		}
+	},
+}
+
+func init() {
+	OutdatedCmd.Flags().BoolVar(&outdatedJSONArg, "json", false, "output JSON")
+}
+
+// versionPin is a version pinned in a file and how it compares with the
+// releases. It is the JSON output of gvs outdated.
+type versionPin struct {
+	File         string `json:"file"`
+	Line         int    `json:"line"`
+	Source       string `json:"source"`
+	Version      string `json:"version"`
+	LatestPatch  string `json:"latest_patch"`
+	LatestStable string `json:"latest_stable"`
+	Outdated     bool   `json:"outdated"`
+	Unsupported  bool   `json:"unsupported"`
+}
+
+// Outdated finds version pins under paths and compares them with the
+// remote releases.
+func Outdated(ctx context.Context, paths []string) ([]*versionPin, error) {
+	var candidates []*versionCandidate
+	for _, root := range paths {
+		found, err := findVersionPins(root)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, found...)
+	}
+
+	versions, err := fetchRemoteVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	patches := latestPatches(versions)
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("no stable releases are found")
+	}
+
+	pins := make([]*versionPin, 0, len(candidates))
+	for _, candidate := range candidates {
+		pins = append(pins, comparePin(candidate, patches))
+	}
+	return pins, nil
+}
+
+// comparePin compares the candidate with patches, the latest patch release
+// of each minor version newest first.
+func comparePin(candidate *versionCandidate, patches []string) *versionPin {
+	pin := &versionPin{
+		File:         candidate.File,
+		Line:         candidate.Line,
+		Source:       candidate.Source,
+		Version:      candidate.Version,
+		LatestStable: strings.TrimPrefix(patches[0], "go"),
+	}
+	latestPatch, outdated, unsupported := supportStatus("go"+strings.TrimLeft(candidate.Version, "vgo"), patches)
+	pin.LatestPatch = strings.TrimPrefix(latestPatch, "go")
+	pin.Outdated = outdated
+	pin.Unsupported = unsupported
+	return pin
+}
+
+// findVersionPins returns the versions of .go-version, go.mod and go.work
+// under root.
+func findVersionPins(root string) ([]*versionCandidate, error) {
+	var candidates []*versionCandidate
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != root && ignoredDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		var candidate *versionCandidate
+		switch d.Name() {
+		case localVersionFile:
+			candidate, err = readVersionFile(sourceGoVersion, p)
+		case "go.mod":
+			candidate, err = readModFile(sourceGoMod, p)
+		case "go.work":
+			candidate, err = goWorkSource{}.lookup(filepath.Dir(p))
+		default:
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		if candidate != nil && candidate.Version != "" {
+			candidates = append(candidates, candidate)
+		}
+		return nil
+	})
+	return candidates, err
+}
+
+func writeOutdated(w io.Writer, pins []*versionPin) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tVERSION\tLATEST PATCH\tLATEST STABLE\tSTATUS")
+	for _, pin := range pins {
+		status := "ok"
+		switch {
+		case pin.Unsupported:
+			status = "unsupported"
+		case pin.Outdated:
+			status = "outdated"
+		}
+		latestPatch := pin.LatestPatch
+		if latestPatch == "" {
+			latestPatch = "-"
+		}
+		fmt.Fprintf(tw, "%s:%d\t%s\t%s\t%s\t%s\n", pin.File, pin.Line, pin.Version, latestPatch, pin.LatestStable, status)
+	}
+	return tw.Flush()
+}
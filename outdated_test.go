@@ -0,0 +1,19 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestVersionPinJSON(t *testing.T) {
+	candidate := &versionCandidate{Source: sourceGoMod, File: "api/go.mod", Line: 3, Version: "1.22.1", Selected: true}
+	pin := comparePin(candidate, []string{"go1.23.2", "go1.22.8"})
+	b, err := json.Marshal(pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"file":"api/go.mod","line":3,"source":"go-mod","version":"1.22.1","latest_patch":"1.22.8","latest_stable":"1.23.2","outdated":true,"unsupported":false}`
+	if string(b) != want {
+		t.Errorf("json = %s, want %s", b, want)
+	}
+}
@@ -2,8 +2,7 @@ package main
 
 import (
 	"fmt"
-	"math"
-	"strconv"
+	goversion "go/version"
 	"strings"
 )
 
@@ -23,6 +22,8 @@ type version struct {
 	major specifyVersion
 	minor specifyVersion
 	patch specifyVersion
+	// prerelease is whether the version names a pre-release, e.g. 1.23rc1.
+	prerelease bool
 }
 
 func compareVersion(str string, version specifyVersion) bool {
@@ -38,14 +39,15 @@ func compareVersion(str string, version specifyVersion) bool {
 
 func parseVersionString(str string) (*version, error) {
 	str = strings.Trim(str, "gov/")
-	splits := strings.Split(str, ".")
-	if len(splits) == 0 {
+	if !goversion.IsValid("go" + str) {
 		return nil, fmt.Errorf("%s is not support format", str)
 	}
+	splits := strings.Split(str, ".")
 	v := &version{
-		major: asterisk{},
-		minor: asterisk{},
-		patch: asterisk{},
+		major:      asterisk{},
+		minor:      asterisk{},
+		patch:      asterisk{},
+		prerelease: isPrerelease(str),
 	}
 	for i, str := range splits {
 		switch i {
@@ -81,18 +83,16 @@ func compareVersionString(numberStrs []string, v *version) bool {
 	return true
 }
 
-func mustParse(numberStr string) int {
-	num, err := strconv.ParseInt(numberStr, 10, 64)
-	if err != nil {
-		panic(err)
-	}
-	return int(num)
+// isPrerelease reports whether the version is a release candidate or a beta.
+func isPrerelease(str string) bool {
+	return strings.Contains(str, "rc") || strings.Contains(str, "beta")
 }
 
-func calcPriority(splitVersion []string) int {
-	var priority int
-	for i, verStr := range splitVersion {
-		priority += mustParse(verStr) * int(math.Pow(10, float64(3-i)))
+// matchVersion reports whether the release name (e.g. go1.22.1) matches v.
+// Pre-releases match only when v names the pre-release.
+func matchVersion(name string, v *version) bool {
+	if !goversion.IsValid(name) || isPrerelease(name) != v.prerelease {
+		return false
 	}
-	return priority
+	return compareVersionString(strings.Split(strings.TrimPrefix(name, "go"), "."), v)
 }
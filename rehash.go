@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var RehashCmd = &cobra.Command{
+	Use:   "rehash",
+	Short: "Create shims for commands of all installed versions",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		if err := Rehash(cmd.Context()); err != nil {
+			fatal(cmd.Context(), err)
+		}
+	},
+}
+
+// Rehash creates a shim for every executable in the bin directories of all
+// installed versions, and removes shims of commands which no longer exist.
+// The shims are scripts if the existing go shim is a script.
+func Rehash(ctx context.Context) error {
+	baseDir, err := checkInit()
+	if err != nil {
+		return err
+	}
+	return rehash(ctx, baseDir, scriptShims(baseDir))
+}
+
+func rehash(ctx context.Context, baseDir string, script bool) error {
+	versions, err := os.ReadDir(filepath.Join(baseDir, "versions"))
+	if err != nil {
+		return err
+	}
+
+	commands := slices.Clone(shimCommands)
+	for _, version := range versions {
+		for _, dir := range versionBinDirs(filepath.Join(baseDir, "versions", version.Name())) {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return err
+			}
+			for _, entry := range entries {
+				info, err := entry.Info()
+				if err != nil || !isExecutable(info) {
+					continue
+				}
+				name := shimName(entry.Name())
+				if isShimName(name) && !slices.Contains(commands, name) {
+					commands = append(commands, name)
+				}
+			}
+		}
+	}
+
+	binDir := filepath.Join(baseDir, "bin")
+	entries, err := os.ReadDir(binDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := shimName(entry.Name())
+		if slices.Contains(commands, name) || !isShim(filepath.Join(binDir, entry.Name())) {
+			continue
+		}
+		debugf(ctx, "remove shim %s", name)
+		if err := os.Remove(filepath.Join(binDir, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	for _, command := range commands {
+		if err := createShim(baseDir, command, script); err != nil {
+			return fmt.Errorf("create %s shim: %w", command, err)
+		}
+	}
+	return nil
+}
+
+// isShim reports whether path is a link to gvs or a script calling gvs.
+func isShim(path string) bool {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return false
+	}
+	if executable, err := os.Executable(); err == nil {
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := filepath.EvalSymlinks(path)
+			if err != nil {
+				return true
+			}
+			resolved, _ := filepath.EvalSymlinks(executable)
+			return target == resolved || shimName(target) == shimName(executable)
+		}
+		if self, err := os.Stat(executable); err == nil && os.SameFile(info, self) {
+			return true
+		}
+	}
+	if !info.Mode().IsRegular() {
+		return false
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return bytes.HasPrefix(b, []byte(scriptShimPrefix)) && strings.Count(string(b), "\n") <= 2
+}
+
+// scriptShimPrefix is the beginning of the script shims.
+const scriptShimPrefix = "#!/bin/bash\ngvs run "
+
+// scriptShims reports whether the go shim is a script created by
+// gvs init --script, so that rehashing keeps the kind of the shims.
+func scriptShims(baseDir string) bool {
+	path := filepath.Join(baseDir, "bin", "go")
+	info, err := os.Lstat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	b := make([]byte, len(scriptShimPrefix))
+	if _, err := io.ReadFull(file, b); err != nil {
+		return false
+	}
+	return string(b) == scriptShimPrefix
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRehashKeepsShimKind(t *testing.T) {
+	for _, script := range []bool{true, false} {
+		name := "links"
+		if script {
+			name = "scripts"
+		}
+		t.Run(name, func(t *testing.T) {
+			ctx := testContext()
+			baseDir, _ := setupHome(t, "go1.22.1")
+			if err := rehash(ctx, baseDir, script); err != nil {
+				t.Fatal(err)
+			}
+			writeFile(t, filepath.Join(baseDir, "versions", "go1.22.1", toolBinDir, "hello"), "")
+			if err := os.Chmod(filepath.Join(baseDir, "versions", "go1.22.1", toolBinDir, "hello"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := Rehash(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, command := range []string{"go", "gofmt", "hello"} {
+				path := filepath.Join(baseDir, "bin", command)
+				if !isShim(path) {
+					t.Fatalf("%s is not a shim", command)
+				}
+				info, err := os.Lstat(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				isScript := false
+				if info.Mode().IsRegular() {
+					b, err := os.ReadFile(path)
+					if err != nil {
+						t.Fatal(err)
+					}
+					isScript = strings.HasPrefix(string(b), scriptShimPrefix)
+				}
+				if isScript != script {
+					t.Errorf("%s is a script = %v, want %v", command, isScript, script)
+				}
+			}
+		})
+	}
+}
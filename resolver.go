@@ -0,0 +1,634 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+const (
+	sourceEnv           = "env"
+	sourceGoVersion     = "go-version"
+	sourceToolVersions  = "tool-versions"
+	sourceGoWork        = "go-work"
+	sourceGoMod         = "go-mod"
+	sourceGitHubActions = "github-actions"
+	sourceDockerfile    = "dockerfile"
+	sourceGlobal        = "global"
+)
+
+const (
+	precedenceNearest = "nearest"
+	precedenceSource  = "source"
+)
+
+const versionEnv = "GVS_VERSION"
+
+var defaultSources = []string{
+	sourceEnv,
+	sourceGoVersion,
+	sourceToolVersions,
+	sourceGoWork,
+	sourceGoMod,
+	sourceGlobal,
+}
+
+// versionCandidate is a version file (or variable) found while resolving.
+// Version is empty when the file exists but does not pin a version.
+type versionCandidate struct {
+	Source   string `json:"source"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Selected bool   `json:"selected"`
+}
+
+// versionSource looks up a version. Sources which are not perDirectory
+// ignore dir and are checked once, either before or after the upward walk
+// depending on their position in the source order.
+type versionSource interface {
+	name() string
+	perDirectory() bool
+	lookup(dir string) (*versionCandidate, error)
+	// watch returns the paths lookup(dir) depends on.
+	watch(dir string) []string
+}
+
+// resolution is the result of resolve. Directories are the directories
+// visited by the upward walk, nearest first.
+type resolution struct {
+	Version     string              `json:"version"`
+	Source      *versionCandidate   `json:"source"`
+	Directories []string            `json:"directories"`
+	Candidates  []*versionCandidate `json:"candidates"`
+
+	// watched are the paths the result depends on.
+	watched []string
+}
+
+type resolver struct {
+	before     []versionSource
+	walk       []versionSource
+	after      []versionSource
+	precedence string
+
+	ceilings        []string
+	stopAtRepoRoot  bool
+	resolveSymlinks bool
+
+	cache      bool
+	configPath string
+}
+
+func newResolver(baseDir string) (*resolver, error) {
+	conf, err := loadConfig(baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := conf.list("sources")
+	if len(names) == 0 {
+		names = defaultSources
+	}
+	disabled := conf.list("disable")
+
+	r := &resolver{precedence: conf.get("precedence")}
+	switch r.precedence {
+	case "":
+		r.precedence = precedenceNearest
+	case precedenceNearest, precedenceSource:
+	default:
+		return nil, fmt.Errorf("unknown precedence %q", r.precedence)
+	}
+
+	for _, ceiling := range filepath.SplitList(conf.get("ceiling-directories")) {
+		if ceiling == "" || !filepath.IsAbs(ceiling) {
+			continue
+		}
+		r.ceilings = append(r.ceilings, physicalPath(ceiling))
+	}
+	if r.stopAtRepoRoot, err = conf.bool("stop-at-repository-root", false); err != nil {
+		return nil, err
+	}
+	if r.resolveSymlinks, err = conf.bool("resolve-symlinks", false); err != nil {
+		return nil, err
+	}
+	if r.cache, err = conf.bool("cache", true); err != nil {
+		return nil, err
+	}
+	r.configPath = filepath.Join(baseDir, configFile)
+
+	for _, name := range names {
+		if slices.Contains(disabled, name) {
+			continue
+		}
+		source, err := newVersionSource(baseDir, name)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case source.perDirectory():
+			r.walk = append(r.walk, source)
+		case len(r.walk) == 0:
+			r.before = append(r.before, source)
+		default:
+			r.after = append(r.after, source)
+		}
+	}
+	return r, nil
+}
+
+func newVersionSource(baseDir, name string) (versionSource, error) {
+	switch name {
+	case sourceEnv:
+		return envSource{}, nil
+	case sourceGoVersion:
+		return goVersionSource{}, nil
+	case sourceToolVersions:
+		return toolVersionsSource{}, nil
+	case sourceGoWork:
+		return goWorkSource{}, nil
+	case sourceGoMod:
+		return goModSource{}, nil
+	case sourceGitHubActions:
+		return gitHubActionsSource{}, nil
+	case sourceDockerfile:
+		return dockerfileSource{}, nil
+	case sourceGlobal:
+		return globalSource{baseDir: baseDir}, nil
+	default:
+		return nil, fmt.Errorf("unknown version source %q", name)
+	}
+}
+
+// decideVersion resolves the version for dir. If modFile is not empty, its
+// toolchain or go directive takes precedence over the files found in dir.
+func decideVersion(ctx context.Context, baseDir, dir, modFile string) (string, error) {
+	candidate, err := decideCandidate(ctx, baseDir, dir, modFile)
+	if err != nil {
+		return "", err
+	}
+	return candidate.Version, nil
+}
+
+// decideCandidate is like decideVersion but returns where the version comes from.
+func decideCandidate(ctx context.Context, baseDir, dir, modFile string) (*versionCandidate, error) {
+	r, err := newResolver(baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var cachePath string
+	if r.cache {
+		cachePath, err = resolveCachePath(baseDir, r.startDirectory(ctx, dir), modFile)
+		if err != nil {
+			return nil, err
+		}
+		if candidate, ok := readResolveCache(ctx, cachePath); ok {
+			debugf(ctx, "use cache %s", cachePath)
+			return candidate, nil
+		}
+	}
+
+	res, err := r.resolve(ctx, dir, modFile)
+	if err != nil {
+		return nil, err
+	}
+	if cachePath != "" {
+		writeResolveCache(ctx, cachePath, res)
+	}
+	return res.Source, nil
+}
+
+func (r *resolver) resolve(ctx context.Context, dir, modFile string) (*resolution, error) {
+	res := &resolution{watched: []string{r.configPath}}
+
+	check := func(source versionSource, dir string) (bool, error) {
+		res.watched = append(res.watched, source.watch(dir)...)
+		candidate, err := source.lookup(dir)
+		if err != nil {
+			return false, err
+		}
+		if candidate == nil {
+			return false, nil
+		}
+		res.Candidates = append(res.Candidates, candidate)
+		if candidate.Version == "" {
+			return false, nil
+		}
+		candidate.Selected = true
+		res.Version = candidate.Version
+		res.Source = candidate
+		if candidate.File != "" {
+			debugf(ctx, "use %s", candidate.File)
+		} else {
+			debugf(ctx, "use %s", candidate.Source)
+		}
+		return true, nil
+	}
+
+	for _, source := range r.before {
+		if ok, err := check(source, ""); ok || err != nil {
+			return res, err
+		}
+	}
+
+	if modFile != "" {
+		if !filepath.IsAbs(modFile) {
+			modFile = filepath.Join(dir, modFile)
+		}
+		if ok, err := check(modFileSource{path: modFile}, ""); ok || err != nil {
+			return res, err
+		}
+	}
+
+	directories := r.parentDirectories(ctx, r.startDirectory(ctx, dir))
+	if r.stopAtRepoRoot {
+		for _, directory := range directories {
+			res.watched = append(res.watched, filepath.Join(directory, ".git"))
+		}
+	}
+	if r.precedence == precedenceSource {
+		res.Directories = directories
+		for _, source := range r.walk {
+			for _, directory := range directories {
+				if ok, err := check(source, directory); ok || err != nil {
+					return res, err
+				}
+			}
+		}
+	} else {
+		for _, directory := range directories {
+			res.Directories = append(res.Directories, directory)
+			for _, source := range r.walk {
+				if ok, err := check(source, directory); ok || err != nil {
+					return res, err
+				}
+			}
+		}
+	}
+
+	for _, source := range r.after {
+		if ok, err := check(source, ""); ok || err != nil {
+			return res, err
+		}
+	}
+	return res, ErrNotFoundGlobalVersion
+}
+
+// startDirectory returns the absolute path the walk starts from, or "" if
+// it cannot be determined.
+func (r *resolver) startDirectory(ctx context.Context, dir string) string {
+	directory, err := filepath.Abs(dir)
+	if err != nil {
+		debugf(ctx, "get %s abs: %v", dir, err)
+		return ""
+	}
+	if r.resolveSymlinks {
+		physical, err := filepath.EvalSymlinks(directory)
+		if err != nil {
+			debugf(ctx, "resolve symlinks of %s: %v", directory, err)
+		} else {
+			directory = physical
+		}
+	}
+	return directory
+}
+
+// parentDir returns the parent of the directory. It is a variable for tests.
+var parentDir = filepath.Dir
+
+// parentDirectories returns dir and its parents, nearest first. The walk
+// does not enter a ceiling directory and, if configured, stops at the first
+// directory containing .git. With resolveSymlinks the physical path is
+// walked, otherwise the logical path of the shell is. A directory is never
+// visited twice, even if its parent is itself.
+func (r *resolver) parentDirectories(ctx context.Context, directory string) []string {
+	if directory == "" {
+		return nil
+	}
+	var dirs []string
+	seen := make(map[string]bool)
+	for !seen[directory] {
+		seen[directory] = true
+		if len(dirs) > 0 && r.isCeiling(directory) {
+			debugf(ctx, "stop at ceiling directory %s", directory)
+			return dirs
+		}
+		dirs = append(dirs, directory)
+		if r.stopAtRepoRoot {
+			if _, err := os.Lstat(filepath.Join(directory, ".git")); err == nil {
+				debugf(ctx, "stop at repository root %s", directory)
+				return dirs
+			}
+		}
+		directory = parentDir(directory)
+	}
+	return dirs
+}
+
+// isCeiling reports whether directory is a ceiling directory. They are
+// compared as physical paths, so that a ceiling stops the walk whether it
+// is given by a logical or a physical path.
+func (r *resolver) isCeiling(directory string) bool {
+	return len(r.ceilings) > 0 && slices.Contains(r.ceilings, physicalPath(directory))
+}
+
+// physicalPath returns path with symlinks resolved, or the cleaned path if
+// they cannot be resolved.
+func physicalPath(path string) string {
+	if physical, err := filepath.EvalSymlinks(path); err == nil {
+		return physical
+	}
+	return filepath.Clean(path)
+}
+
+type (
+	envSource           struct{}
+	goVersionSource     struct{}
+	toolVersionsSource  struct{}
+	goWorkSource        struct{}
+	goModSource         struct{}
+	gitHubActionsSource struct{}
+	dockerfileSource    struct{}
+	globalSource        struct{ baseDir string }
+	modFileSource       struct{ path string }
+)
+
+func (envSource) name() string           { return sourceEnv }
+func (goVersionSource) name() string     { return sourceGoVersion }
+func (toolVersionsSource) name() string  { return sourceToolVersions }
+func (goWorkSource) name() string        { return sourceGoWork }
+func (goModSource) name() string         { return sourceGoMod }
+func (gitHubActionsSource) name() string { return sourceGitHubActions }
+func (dockerfileSource) name() string    { return sourceDockerfile }
+func (globalSource) name() string        { return sourceGlobal }
+func (modFileSource) name() string       { return "modfile" }
+
+func (envSource) perDirectory() bool           { return false }
+func (goVersionSource) perDirectory() bool     { return true }
+func (toolVersionsSource) perDirectory() bool  { return true }
+func (goWorkSource) perDirectory() bool        { return true }
+func (goModSource) perDirectory() bool         { return true }
+func (gitHubActionsSource) perDirectory() bool { return true }
+func (dockerfileSource) perDirectory() bool    { return true }
+func (globalSource) perDirectory() bool        { return false }
+func (modFileSource) perDirectory() bool       { return false }
+
+func (envSource) watch(string) []string { return nil }
+
+func (s globalSource) watch(string) []string {
+	return []string{filepath.Join(s.baseDir, globalVersionFile)}
+}
+
+func (s modFileSource) watch(string) []string { return []string{s.path} }
+
+func (goVersionSource) watch(dir string) []string {
+	return []string{filepath.Join(dir, localVersionFile)}
+}
+
+func (toolVersionsSource) watch(dir string) []string {
+	return []string{filepath.Join(dir, ".tool-versions")}
+}
+
+func (goWorkSource) watch(dir string) []string {
+	return []string{filepath.Join(dir, "go.work")}
+}
+
+func (goModSource) watch(dir string) []string {
+	return []string{filepath.Join(dir, "go.mod")}
+}
+
+func (dockerfileSource) watch(dir string) []string {
+	return []string{filepath.Join(dir, "Dockerfile")}
+}
+
+func (gitHubActionsSource) watch(dir string) []string {
+	workflowDir := filepath.Join(dir, ".github", "workflows")
+	paths := []string{workflowDir}
+	for _, pattern := range []string{"*.yml", "*.yaml"} {
+		matches, _ := filepath.Glob(filepath.Join(workflowDir, pattern))
+		paths = append(paths, matches...)
+	}
+	return paths
+}
+
+func (s envSource) lookup(string) (*versionCandidate, error) {
+	v, ok := os.LookupEnv(versionEnv)
+	if !ok {
+		return nil, nil
+	}
+	return &versionCandidate{Source: s.name(), Version: strings.TrimSpace(v)}, nil
+}
+
+func (s globalSource) lookup(string) (*versionCandidate, error) {
+	return readVersionFile(s.name(), filepath.Join(s.baseDir, globalVersionFile))
+}
+
+func (s goVersionSource) lookup(dir string) (*versionCandidate, error) {
+	return readVersionFile(s.name(), filepath.Join(dir, localVersionFile))
+}
+
+// readVersionFile reads the first non-empty line of a file.
+func readVersionFile(source, path string) (*versionCandidate, error) {
+	candidate := &versionCandidate{Source: source, File: path}
+	err := scanFile(path, func(line int, text string) bool {
+		if text = strings.TrimSpace(text); text == "" {
+			return true
+		}
+		candidate.Line = line
+		candidate.Version = text
+		return false
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return candidate, nil
+}
+
+func (s toolVersionsSource) lookup(dir string) (*versionCandidate, error) {
+	candidate := &versionCandidate{Source: s.name(), File: filepath.Join(dir, ".tool-versions")}
+	err := scanFile(candidate.File, func(line int, text string) bool {
+		text, _, _ = strings.Cut(text, "#")
+		fields := strings.Fields(text)
+		if len(fields) < 2 || (fields[0] != "golang" && fields[0] != "go") {
+			return true
+		}
+		for _, v := range fields[1:] {
+			if v == "system" || strings.Contains(v, ":") {
+				continue
+			}
+			candidate.Line = line
+			candidate.Version = v
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return candidate, nil
+}
+
+func (s goWorkSource) lookup(dir string) (*versionCandidate, error) {
+	path := filepath.Join(dir, "go.work")
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	gowork, err := modfile.ParseWork(path, bytes, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	candidate := &versionCandidate{Source: s.name(), File: path}
+	if gowork.Go != nil {
+		candidate.Line = gowork.Go.Syntax.Start.Line
+		candidate.Version = gowork.Go.Version
+	}
+	return candidate, nil
+}
+
+// lookup ignores the module file which cannot be read, so that go reports
+// it instead of gvs and the version is decided by the directories.
+func (s modFileSource) lookup(string) (*versionCandidate, error) {
+	candidate, err := readModFile(s.name(), s.path)
+	if err != nil {
+		return nil, nil
+	}
+	return candidate, nil
+}
+
+func (s goModSource) lookup(dir string) (*versionCandidate, error) {
+	return readModFile(s.name(), filepath.Join(dir, "go.mod"))
+}
+
+// readModFile reads the toolchain directive of a go.mod formatted file,
+// falling back to its go directive.
+func readModFile(source, path string) (*versionCandidate, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	gomod, err := modfile.Parse(path, bytes, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	candidate := &versionCandidate{Source: source, File: path}
+	if gomod.Toolchain != nil && gomod.Toolchain.Name != "default" {
+		candidate.Line = gomod.Toolchain.Syntax.Start.Line
+		candidate.Version = strings.TrimLeft(gomod.Toolchain.Name, "go")
+	} else if gomod.Go != nil {
+		candidate.Line = gomod.Go.Syntax.Start.Line
+		candidate.Version = gomod.Go.Version
+	}
+	return candidate, nil
+}
+
+func (s gitHubActionsSource) lookup(dir string) (*versionCandidate, error) {
+	var workflows []string
+	for _, pattern := range []string{"*.yml", "*.yaml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, ".github", "workflows", pattern))
+		if err != nil {
+			return nil, err
+		}
+		workflows = append(workflows, matches...)
+	}
+	slices.Sort(workflows)
+
+	for _, workflow := range workflows {
+		candidate := &versionCandidate{Source: s.name(), File: workflow}
+		err := scanFile(workflow, func(line int, text string) bool {
+			text = strings.TrimLeft(strings.TrimSpace(text), "- ")
+			value, ok := strings.CutPrefix(text, "go-version:")
+			if !ok {
+				return true
+			}
+			value, _, _ = strings.Cut(value, "#")
+			value = strings.Trim(strings.TrimSpace(value), `"'`)
+			// aliases like stable and ranges like ^1.21 are not versions.
+			if value == "" || value[0] < '0' || value[0] > '9' || strings.ContainsAny(value, "${[") {
+				return true
+			}
+			candidate.Line = line
+			candidate.Version = strings.TrimSuffix(value, ".x")
+			return false
+		})
+		if err != nil {
+			return nil, err
+		}
+		if candidate.Version != "" {
+			return candidate, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s dockerfileSource) lookup(dir string) (*versionCandidate, error) {
+	candidate := &versionCandidate{Source: s.name(), File: filepath.Join(dir, "Dockerfile")}
+	err := scanFile(candidate.File, func(line int, text string) bool {
+		fields := strings.Fields(text)
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "FROM") {
+			return true
+		}
+		image := fields[1]
+		if strings.HasPrefix(image, "--platform=") && len(fields) > 2 {
+			image = fields[2]
+		}
+		name, tag, ok := strings.Cut(image, ":")
+		if !ok || (name != "golang" && !strings.HasSuffix(name, "/golang")) {
+			return true
+		}
+		tag, _, _ = strings.Cut(tag, "@")
+		tag, _, _ = strings.Cut(tag, "-")
+		if tag == "" || tag[0] < '0' || tag[0] > '9' {
+			return true
+		}
+		candidate.Line = line
+		candidate.Version = tag
+		return false
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return candidate, nil
+}
+
+// scanFile calls fn for each line of path until fn returns false.
+func scanFile(path string, fn func(line int, text string) bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if !fn(line, scanner.Text()) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
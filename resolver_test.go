@@ -0,0 +1,332 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParentDirectories(t *testing.T) {
+	tests := []struct {
+		name           string
+		start          string
+		ceiling        string
+		stopAtRepoRoot bool
+		resolveLinks   bool
+		git            string
+		want           []string
+	}{
+		{
+			name:  "logical walk stops below the ceiling",
+			start: "link/proj/sub",
+			want:  []string{"link/proj/sub", "link/proj", "link"},
+		},
+		{
+			name:    "physical ceiling stops the logical walk",
+			start:   "link/proj/sub",
+			ceiling: "real/ws",
+			want:    []string{"link/proj/sub", "link/proj"},
+		},
+		{
+			name:         "logical ceiling stops the physical walk",
+			start:        "link/proj/sub",
+			ceiling:      "link",
+			resolveLinks: true,
+			want:         []string{"real/ws/proj/sub", "real/ws/proj"},
+		},
+		{
+			name:    "ceiling is the start directory",
+			start:   "real/ws/proj",
+			ceiling: "real/ws/proj",
+			want:    []string{"real/ws/proj", "real/ws", "real"},
+		},
+		{
+			name:           "stop at .git directory",
+			start:          "real/ws/proj/sub",
+			stopAtRepoRoot: true,
+			git:            "real/ws/.git",
+			want:           []string{"real/ws/proj/sub", "real/ws/proj", "real/ws"},
+		},
+		{
+			name:           "stop at .git of the logical path",
+			start:          "link/proj/sub",
+			stopAtRepoRoot: true,
+			git:            "real/ws/.git",
+			want:           []string{"link/proj/sub", "link/proj", "link"},
+		},
+		{
+			name:  ".git is ignored by default",
+			start: "real/ws/proj",
+			git:   "real/ws/.git",
+			want:  []string{"real/ws/proj", "real/ws", "real"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseDir, _ := setupHome(t)
+			root := physicalPath(t.TempDir())
+			if err := os.MkdirAll(filepath.Join(root, "real", "ws", "proj", "sub"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Symlink(filepath.Join(root, "real", "ws"), filepath.Join(root, "link")); err != nil {
+				t.Skip(err)
+			}
+			if tt.git != "" {
+				if err := os.Mkdir(filepath.Join(root, tt.git), 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// root is always a ceiling not to walk out of the test.
+			t.Setenv("GVS_CEILING_DIRECTORIES", root+string(filepath.ListSeparator)+filepath.Join(root, tt.ceiling))
+			t.Setenv("GVS_STOP_AT_REPOSITORY_ROOT", boolString(tt.stopAtRepoRoot))
+			t.Setenv("GVS_RESOLVE_SYMLINKS", boolString(tt.resolveLinks))
+
+			r, err := newResolver(baseDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := testContext()
+			got := r.parentDirectories(ctx, r.startDirectory(ctx, filepath.Join(root, tt.start)))
+			var want []string
+			for _, dir := range tt.want {
+				want = append(want, filepath.Join(root, dir))
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("parentDirectories() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestParentDirectoriesStopsAtLoop(t *testing.T) {
+	parents := map[string]string{"/a/b/c": "/a/b", "/a/b": "/a/b"}
+	defer func(orig func(string) string) { parentDir = orig }(parentDir)
+	parentDir = func(dir string) string { return parents[dir] }
+
+	r := &resolver{}
+	got := r.parentDirectories(testContext(), "/a/b/c")
+	if want := []string{"/a/b/c", "/a/b"}; !slices.Equal(got, want) {
+		t.Errorf("parentDirectories() = %q, want %q", got, want)
+	}
+}
+
+func boolString(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+func TestVersionSourceLookup(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		file     string
+		content  string
+		wantNil  bool
+		wantLine int
+		want     string
+	}{
+		{name: ".go-version", source: sourceGoVersion, file: ".go-version", content: "\n 1.22.1 \n", wantLine: 2, want: "1.22.1"},
+		{name: "empty .go-version", source: sourceGoVersion, file: ".go-version", content: "\n\n"},
+		{name: "missing .go-version", source: sourceGoVersion, wantNil: true},
+		{name: ".tool-versions golang", source: sourceToolVersions, file: ".tool-versions", content: "nodejs 20.1.0\ngolang 1.21.5 # pinned\n", wantLine: 2, want: "1.21.5"},
+		{name: ".tool-versions skips system and refs", source: sourceToolVersions, file: ".tool-versions", content: "go system ref:abc 1.22\n", wantLine: 1, want: "1.22"},
+		{name: ".tool-versions without go", source: sourceToolVersions, file: ".tool-versions", content: "nodejs 20.1.0\n"},
+		{name: "go.work", source: sourceGoWork, file: "go.work", content: "go 1.22.1\n\nuse ./a\n", wantLine: 1, want: "1.22.1"},
+		{name: "go.work without go", source: sourceGoWork, file: "go.work", content: "use ./a\n"},
+		{name: "go.mod toolchain", source: sourceGoMod, file: "go.mod", content: "module m\n\ngo 1.21\n\ntoolchain go1.22.3\n", wantLine: 5, want: "1.22.3"},
+		{name: "go.mod default toolchain", source: sourceGoMod, file: "go.mod", content: "module m\n\ngo 1.21\n\ntoolchain default\n", wantLine: 3, want: "1.21"},
+		{
+			name:   "GitHub Actions",
+			source: sourceGitHubActions,
+			file:   ".github/workflows/ci.yml",
+			content: `jobs:
+  test:
+    strategy:
+      matrix:
+        go: [1.21, 1.22]
+    steps:
+      - uses: actions/setup-go@v5
+        with:
+          go-version: ${{ matrix.go }}
+      - uses: actions/setup-go@v5
+        with:
+          go-version: stable
+      - uses: actions/setup-go@v5
+        with:
+          go-version: '1.22.x' # latest patch
+`,
+			wantLine: 15,
+			want:     "1.22",
+		},
+		{name: "GitHub Actions list item", source: sourceGitHubActions, file: ".github/workflows/ci.yaml", content: "- go-version: \"1.21.5\"\n", wantLine: 1, want: "1.21.5"},
+		{name: "GitHub Actions with aliases only", source: sourceGitHubActions, file: ".github/workflows/ci.yml", content: "go-version: oldstable\ngo-version: '^1.21'\n", wantNil: true},
+		{name: "Dockerfile", source: sourceDockerfile, file: "Dockerfile", content: "FROM --platform=$BUILDPLATFORM golang:1.22.1-alpine AS build\nFROM scratch\n", wantLine: 1, want: "1.22.1"},
+		{name: "Dockerfile with registry and digest", source: sourceDockerfile, file: "Dockerfile", content: "from docker.io/library/golang:1.21@sha256:abc\n", wantLine: 1, want: "1.21"},
+		{name: "Dockerfile with latest", source: sourceDockerfile, file: "Dockerfile", content: "FROM golang:latest\nFROM golang\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseDir, project := setupHome(t)
+			if tt.file != "" {
+				writeFile(t, filepath.Join(project, tt.file), tt.content)
+			}
+			source, err := newVersionSource(baseDir, tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := source.lookup(project)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantNil {
+				if got != nil {
+					t.Fatalf("lookup() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("lookup() = nil")
+			}
+			if got.Version != tt.want || got.Line != tt.wantLine || got.Source != tt.source {
+				t.Errorf("lookup() = %+v, want version %q at line %d from %s", got, tt.want, tt.wantLine, tt.source)
+			}
+		})
+	}
+}
+
+func TestDecideVersionPrecedence(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     string
+		env        string
+		files      map[string]string
+		dir        string
+		want       string
+		wantSource string
+	}{
+		{
+			name:       ".go-version wins over go.mod in a directory",
+			files:      map[string]string{".go-version": "1.22.1", "go.mod": "module m\n\ngo 1.21\n"},
+			want:       "1.22.1",
+			wantSource: sourceGoVersion,
+		},
+		{
+			name:       ".tool-versions wins over go.work",
+			files:      map[string]string{".tool-versions": "golang 1.21.5\n", "go.work": "go 1.22.1\n", "go.mod": "module m\n\ngo 1.21\n"},
+			want:       "1.21.5",
+			wantSource: sourceToolVersions,
+		},
+		{
+			name:       "go.work wins over go.mod",
+			files:      map[string]string{"go.work": "go 1.22.1\n", "go.mod": "module m\n\ngo 1.21\n"},
+			want:       "1.22.1",
+			wantSource: sourceGoWork,
+		},
+		{
+			name:       "empty .go-version falls through",
+			files:      map[string]string{".go-version": "\n", "go.mod": "module m\n\ngo 1.21\n"},
+			want:       "1.21",
+			wantSource: sourceGoMod,
+		},
+		{
+			name:       "nearer go.mod wins over farther .go-version",
+			files:      map[string]string{".go-version": "1.22.1", "sub/go.mod": "module m\n\ngo 1.21\n"},
+			dir:        "sub/pkg",
+			want:       "1.21",
+			wantSource: sourceGoMod,
+		},
+		{
+			name:       "farther .go-version wins by source precedence",
+			config:     "precedence = source\n",
+			files:      map[string]string{".go-version": "1.22.1", "sub/go.mod": "module m\n\ngo 1.21\n"},
+			dir:        "sub/pkg",
+			want:       "1.22.1",
+			wantSource: sourceGoVersion,
+		},
+		{
+			name:       "order of sources",
+			config:     "sources = go-mod, go-version, global\n",
+			files:      map[string]string{".go-version": "1.22.1", "go.mod": "module m\n\ngo 1.21\n"},
+			want:       "1.21",
+			wantSource: sourceGoMod,
+		},
+		{
+			name:       "disabled source",
+			config:     "disable = go-version\n",
+			files:      map[string]string{".go-version": "1.22.1", "go.mod": "module m\n\ngo 1.21\n"},
+			want:       "1.21",
+			wantSource: sourceGoMod,
+		},
+		{
+			name:       "GitHub Actions and Dockerfile are disabled by default",
+			files:      map[string]string{".github/workflows/ci.yml": "go-version: 1.21\n", "Dockerfile": "FROM golang:1.21\n"},
+			want:       "1.22.1",
+			wantSource: sourceGlobal,
+		},
+		{
+			name:       "GitHub Actions before Dockerfile",
+			config:     "sources = env, github-actions, dockerfile, global\n",
+			files:      map[string]string{".github/workflows/ci.yml": "go-version: 1.21.5\n", "Dockerfile": "FROM golang:1.21.4\n"},
+			want:       "1.21.5",
+			wantSource: sourceGitHubActions,
+		},
+		{
+			name:       "nearer Dockerfile wins over farther GitHub Actions",
+			config:     "sources = github-actions, dockerfile, global\n",
+			files:      map[string]string{".github/workflows/ci.yml": "go-version: 1.21.5\n", "sub/Dockerfile": "FROM golang:1.21.4\n"},
+			dir:        "sub",
+			want:       "1.21.4",
+			wantSource: sourceDockerfile,
+		},
+		{
+			name:       "GVS_VERSION wins over files",
+			env:        "1.21.3",
+			files:      map[string]string{".go-version": "1.22.1"},
+			want:       "1.21.3",
+			wantSource: sourceEnv,
+		},
+		{
+			name:       "GVS_VERSION after the walk",
+			config:     "sources = go-version, env, global\n",
+			env:        "1.21.3",
+			files:      map[string]string{".go-version": "1.22.1"},
+			want:       "1.22.1",
+			wantSource: sourceGoVersion,
+		},
+		{
+			name:       "global version without files",
+			want:       "1.22.1",
+			wantSource: sourceGlobal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseDir, project := setupHome(t)
+			writeFile(t, filepath.Join(baseDir, globalVersionFile), "1.22.1")
+			if tt.config != "" {
+				writeFile(t, filepath.Join(baseDir, configFile), tt.config)
+			}
+			if tt.env != "" {
+				t.Setenv(versionEnv, tt.env)
+			}
+			for name, content := range tt.files {
+				writeFile(t, filepath.Join(project, name), content)
+			}
+			dir := filepath.Join(project, tt.dir)
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := decideCandidate(testContext(), baseDir, dir, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Version != tt.want || got.Source != tt.wantSource {
+				t.Errorf("decideCandidate() = %s from %s, want %s from %s", got.Version, got.Source, tt.want, tt.wantSource)
+			}
+		})
+	}
+}
@@ -4,15 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
+	goversion "go/version"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"slices"
 	"strings"
 
 	"github.com/spf13/cobra"
-	"golang.org/x/mod/modfile"
 )
 
 var (
@@ -21,33 +21,86 @@ var (
 )
 
 var RunCmd = &cobra.Command{
-	Use:   "run [go|gofmt]",
-	Short: "Run command(go or gofmt)",
+	Use:   "run [command]",
+	Short: "Run command(go, gofmt or tools) of the selected version",
 	Args:  cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		verbose = runVerboseArg
 		command := args[0]
-		if !slices.Contains([]string{"go", "gofmt"}, command) {
-			cmd.Usage()
-			os.Exit(1)
-		}
 		var commandArgs []string
 		if len(args) > 1 {
 			commandArgs = args[1:]
 		}
-		if err := Run(cmd.Context(), runVersionArg, command, commandArgs); err != nil {
-			var extErr *exec.ExitError
-			if errors.As(err, &extErr) {
-				os.Exit(extErr.ExitCode())
-			} else if errors.Is(err, ErrNotFoundGlobalVersion) {
-				fatal(cmd.Context(), fmt.Errorf("no specify version. Run `nvs use`"))
-			} else {
-				fatal(cmd.Context(), err)
-			}
-		}
+		runAndExit(cmd.Context(), runVersionArg, command, commandArgs)
 	},
 }
 
+// shimCommands always have shims, even before any version is installed.
+var shimCommands = []string{"go", "gofmt"}
+
+// isShimName reports whether a shim may have the name. Names starting with
+// gvs are left to gvs itself.
+func isShimName(name string) bool {
+	return !strings.HasPrefix(name, "gvs")
+}
+
+// calledAsShim reports whether gvs is called by the name of a shim, which is
+// one of shimCommands or a shim in $HOME/.gvs/bin. gvs renamed to any other
+// name runs as gvs.
+func calledAsShim(name string) bool {
+	if !isShimName(name) {
+		return false
+	}
+	if slices.Contains(shimCommands, name) {
+		return true
+	}
+	baseDir, err := checkInit()
+	if err != nil {
+		return false
+	}
+	for _, file := range []string{name, name + ".exe"} {
+		if isShim(filepath.Join(baseDir, "bin", file)) {
+			return true
+		}
+	}
+	return false
+}
+
+// shimName returns the command name gvs is called by.
+func shimName(arg0 string) string {
+	return strings.TrimSuffix(filepath.Base(arg0), ".exe")
+}
+
+// runShim is the fast path for the shim script "gvs run <command> -- args",
+// which skips cobra.
+func runShim(ctx context.Context, args []string) bool {
+	if len(args) < 3 || args[0] != "run" || args[2] != "--" || strings.HasPrefix(args[1], "-") {
+		return false
+	}
+	verbose = false
+	runAndExit(ctx, autoVersion, args[1], args[3:])
+	return true
+}
+
+func runAndExit(ctx context.Context, versionStr, command string, args []string) {
+	exitOnError(ctx, Run(ctx, versionStr, command, args))
+}
+
+// exitOnError exits with the exit code of a failed child, or reports err.
+func exitOnError(ctx context.Context, err error) {
+	if err == nil {
+		return
+	}
+	var extErr *exec.ExitError
+	if errors.As(err, &extErr) {
+		os.Exit(exitCode(extErr))
+	} else if errors.Is(err, ErrNotFoundGlobalVersion) {
+		fatal(ctx, fmt.Errorf("no specify version. Run `nvs use`"))
+	} else {
+		fatal(ctx, err)
+	}
+}
+
 func init() {
 	RunCmd.Flags().StringVar(&runVersionArg, "version", autoVersion, "specify running version")
 	RunCmd.Flags().BoolVarP(&runVerboseArg, "verbose", "v", false, "output verbose")
@@ -57,160 +110,322 @@ const autoVersion = "auto"
 
 var ErrNotFoundGlobalVersion = fmt.Errorf("not found global version")
 
-func decideVersion(ctx context.Context, baseDir string) (string, error) {
-	globalVersion := func() (string, error) {
-		v, err := os.ReadFile(filepath.Join(baseDir, globalVersionFile))
-		if err != nil {
-			if os.IsNotExist(err) {
-				return "", ErrNotFoundGlobalVersion
-			}
-			return "", err
-		}
-		return string(v), nil
+func Run(ctx context.Context, versionStr string, command string, args []string) error {
+	baseDir, err := checkInit()
+	if err != nil {
+		return err
+	}
+
+	dir, modFile := ".", ""
+	if command == "go" {
+		dir, modFile = goTarget(args)
+	}
+	versionDir, err := installedVersionDir(ctx, baseDir, versionStr, dir, modFile)
+	if err != nil {
+		return err
+	}
+	cmd, err := versionCommand(ctx, baseDir, versionDir, command, args)
+	if err != nil {
+		return err
+	}
+	return execCommand(cmd.Path, cmd.Args[1:], cmd.Env)
+}
+
+// versionCommand returns the command of the installed version in versionDir
+// with the environment given by toolchainEnviron.
+func versionCommand(ctx context.Context, baseDir, versionDir, command string, args []string) (*exec.Cmd, error) {
+	if err := checkSupport(ctx, baseDir, versionDir, false); err != nil {
+		return nil, err
+	}
+	path, err := findCommand(versionDir, command)
+	if err != nil {
+		return nil, err
 	}
+	cmd := exec.Command(path, args...)
+	cmd.Env = toolchainEnviron(os.Environ(), versionDir)
+	return cmd, nil
+}
 
-	for dir := "."; ; dir = filepath.Join("..", dir) {
-		directory, err := filepath.Abs(dir)
+// installedVersionDir returns the directory of versionStr, or of the version
+// decided for dir and modFile if versionStr is autoVersion. The version is
+// downloaded if it is not installed.
+func installedVersionDir(ctx context.Context, baseDir, versionStr, dir, modFile string) (string, error) {
+	var err error
+	if versionStr == autoVersion {
+		versionStr, err = decideVersion(ctx, baseDir, dir, modFile)
 		if err != nil {
-			debugf(ctx, "get %s abs: %v", dir, err)
-			return globalVersion()
+			return "", err
 		}
-
-		if goVersionFile, err := os.Open(filepath.Join(directory, ".go-version")); err == nil {
-			debugf(ctx, "use .go-version")
-			b, err := io.ReadAll(goVersionFile)
-			if err != nil {
+	}
+	parsedVersion, err := parseVersionString(versionStr)
+	if err != nil {
+		return "", err
+	}
+	nodeBasePath, err := findLocalVersion(baseDir, parsedVersion)
+	if err != nil {
+		if errors.Is(err, ErrNotFoundLocalVersion) {
+			warnf(ctx, "download %s version", versionStr)
+			if err := Download(ctx, parsedVersion); err != nil {
 				return "", err
 			}
-			return strings.TrimRight(string(b), "\n"), nil
-		}
-		if goworkFile, err := os.Open(filepath.Join(directory, "go.work")); err == nil {
-			bytes, err := io.ReadAll(goworkFile)
+			nodeBasePath, err = findLocalVersion(baseDir, parsedVersion)
 			if err != nil {
 				return "", err
 			}
-			goworkFile.Close()
+		} else {
+			return "", err
+		}
+	}
+	debugf(ctx, "use %s", nodeBasePath)
+	return filepath.Join(baseDir, "versions", nodeBasePath), nil
+}
 
-			gowork, err := modfile.ParseWork(goworkFile.Name(), bytes, nil)
-			if err != nil {
-				return "", err
-			}
+// toolchainEnviron returns environ for running commands of versionDir.
+// GOROOT is always set to versionDir and its bin directories are put first
+// on PATH, so that nested go commands run the same version. GOTOOLCHAIN is
+// set to local unless it is already set, so that go does not switch the
+// toolchain by itself.
+func toolchainEnviron(environ []string, versionDir string) []string {
+	env := make([]string, 0, len(environ)+3)
+	var path string
+	hasToolchain := false
+	for _, kv := range environ {
+		key, value, _ := strings.Cut(kv, "=")
+		switch key {
+		case "GOROOT":
+			continue
+		case "PATH":
+			path = value
+			continue
+		case "GOTOOLCHAIN":
+			hasToolchain = true
+		}
+		env = append(env, kv)
+	}
 
-			if gowork.Go != nil {
-				debugf(ctx, "use go.work")
-				return gowork.Go.Version, nil
-			}
+	dirs := versionBinDirs(versionDir)
+	for _, dir := range filepath.SplitList(path) {
+		if !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
 		}
-		if gomodFile, err := os.Open(filepath.Join(directory, "go.mod")); err == nil {
-			bytes, err := io.ReadAll(gomodFile)
-			if err != nil {
-				return "", err
-			}
-			gomodFile.Close()
+	}
+	env = append(env,
+		"GOROOT="+versionDir,
+		"PATH="+strings.Join(dirs, string(filepath.ListSeparator)),
+	)
+	if !hasToolchain {
+		env = append(env, "GOTOOLCHAIN=local")
+	}
+	return env
+}
 
-			gomod, err := modfile.Parse(gomodFile.Name(), bytes, nil)
-			if err != nil {
-				return "", err
-			}
+// pinnedEnviron is like toolchainEnviron, but GOTOOLCHAIN is always local.
+// Commands comparing versions use it, because a toolchain line in go.mod
+// would otherwise make go switch to a newer version silently.
+func pinnedEnviron(environ []string, versionDir string) []string {
+	return setEnviron(toolchainEnviron(environ, versionDir), "GOTOOLCHAIN", "local")
+}
 
-			debugf(ctx, "use go.mod")
-			if gomod.Toolchain != nil {
-				return strings.TrimLeft(gomod.Toolchain.Name, "go"), nil
-			}
-			if gomod.Go != nil {
-				return gomod.Go.Version, nil
-			}
-		}
-		if directory == "/" {
-			v, err := globalVersion()
-			if err != nil {
-				return "", err
+// toolBinDir is the directory in a version for tools built with the version.
+const toolBinDir = "gvs-bin"
+
+// versionBinDirs returns the directories of commands of the version.
+func versionBinDirs(versionDir string) []string {
+	return []string{filepath.Join(versionDir, "bin"), filepath.Join(versionDir, toolBinDir)}
+}
+
+func findCommand(versionDir, command string) (string, error) {
+	for _, dir := range versionBinDirs(versionDir) {
+		path := filepath.Join(dir, command)
+		for _, name := range []string{path, path + ".exe"} {
+			if info, err := os.Stat(name); err == nil && isExecutable(info) {
+				return name, nil
 			}
-			return v, nil
 		}
 	}
+	return "", fmt.Errorf("%s is not found in %s", command, filepath.Base(versionDir))
 }
 
-func Run(ctx context.Context, versionStr string, command string, args []string) error {
-	baseDir, err := checkInit()
-	if err != nil {
-		return err
+// lookPathIn is like exec.LookPath but searches the PATH of environ.
+func lookPathIn(command string, environ []string) (string, error) {
+	if strings.ContainsRune(command, filepath.Separator) || strings.ContainsRune(command, '/') {
+		return command, nil
 	}
-
-	var parsedVersion *version
-	if versionStr != autoVersion {
-		parsedVersion, err = parseVersionString(versionStr)
-		if err != nil {
-			return err
-		}
-	} else {
-		versionStr, err = decideVersion(ctx, baseDir)
-		if err != nil {
-			return err
-		}
-		parsedVersion, err = parseVersionString(versionStr)
-		if err != nil {
-			return err
+	var path string
+	for _, kv := range environ {
+		if value, ok := strings.CutPrefix(kv, "PATH="); ok {
+			path = value
 		}
 	}
-	nodeBasePath, err := findLocalVersion(baseDir, parsedVersion)
-	if err != nil {
-		if errors.Is(err, ErrNotFoundLocalVersion) {
-			warnf(ctx, "download %s version", versionStr)
-			if err := Download(ctx, parsedVersion); err != nil {
-				return err
-			}
-			nodeBasePath, err = findLocalVersion(baseDir, parsedVersion)
-			if err != nil {
-				return err
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			dir = "."
+		}
+		for _, name := range []string{filepath.Join(dir, command), filepath.Join(dir, command+".exe")} {
+			if info, err := os.Stat(name); err == nil && isExecutable(info) {
+				return name, nil
 			}
-		} else {
-			return err
 		}
 	}
-	debugf(ctx, "use %s", nodeBasePath)
+	return "", &exec.Error{Name: command, Err: exec.ErrNotFound}
+}
+
+func isExecutable(info os.FileInfo) bool {
+	if !info.Mode().IsRegular() {
+		return false
+	}
+	return info.Mode().Perm()&0o111 != 0 || strings.HasSuffix(info.Name(), ".exe")
+}
 
-	cmd := exec.CommandContext(ctx, filepath.Join(baseDir, "versions", nodeBasePath, "bin", command), args...)
+// runCommand runs the command as a child, forwarding signals to it.
+func runCommand(path string, args, env []string) error {
+	cmd := exec.Command(path, args...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
+	cmd.Env = env
+	return startAndWait(cmd)
+}
 
+// startAndWait runs cmd, forwarding signals sent to gvs to it.
+func startAndWait(cmd *exec.Cmd) error {
+	// signals are caught before the start, so that no signal kills gvs
+	// without the child.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardSignals...)
+	defer signal.Stop(signals)
 	if err := cmd.Start(); err != nil {
 		return err
 	}
-	if err := cmd.Wait(); err != nil {
-		return err
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				cmd.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return cmd.Wait()
+}
+
+// exitCode returns the exit code of the child like a shell does, which is
+// 128+n when the child is killed by the signal n.
+func exitCode(err *exec.ExitError) int {
+	if code, ok := signalExitCode(err.ProcessState); ok {
+		return code
 	}
-	return nil
+	return err.ExitCode()
 }
 
-type localFile struct {
-	name     string
-	priority int
+// goTarget returns the directory given by -C and the module file given by
+// -modfile (in args or GOFLAGS) of the go command line args. -C must be the
+// first flag after the command names, but it is also accepted before them.
+// The flags end at the first package or file, -args or --, because the rest
+// is for the program run by go run or go test.
+func goTarget(args []string) (dir, modFile string) {
+	dir = "."
+	if len(args) > 0 {
+		if value, n, ok := flagValue(args, "C"); ok {
+			dir, args = value, args[n:]
+		}
+	}
+	commandNames := 1
+	switch {
+	case len(args) == 0:
+	case slices.Contains(goCommandsWithSubcommand, args[0]):
+		commandNames = 2
+	case args[0] == "tool":
+		// the flags are for the tool.
+		commandNames = len(args)
+	}
+
+	flags := strings.Fields(os.Getenv("GOFLAGS"))
+	for i := min(commandNames, len(args)); i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || arg == "-args" || arg == "--args" || !strings.HasPrefix(arg, "-") {
+			break
+		}
+		if value, n, ok := flagValue(args[i:], "C"); ok {
+			dir = value
+			i += n - 1
+			continue
+		}
+		flags = append(flags, arg)
+		name := strings.TrimLeft(arg, "-")
+		if !strings.Contains(name, "=") && slices.Contains(goFlagsWithValue, name) && i+1 < len(args) {
+			i++
+			flags = append(flags, args[i])
+		}
+	}
+	for i := range flags {
+		if value, _, ok := flagValue(flags[i:], "modfile"); ok {
+			modFile = value
+		}
+	}
+	return dir, modFile
+}
+
+// goCommandsWithSubcommand are the go commands whose second arg is a command
+// name, e.g. go mod tidy.
+var goCommandsWithSubcommand = []string{"mod", "work"}
+
+// goFlagsWithValue are the flags of the go commands which take the next arg as
+// the value when it is not given by -flag=value. The other flags are boolean.
+var goFlagsWithValue = []string{
+	// build flags
+	"C", "asmflags", "buildmode", "compiler", "covermode", "coverpkg", "exec", "gccgoflags",
+	"gcflags", "installsuffix", "ldflags", "mod", "modfile", "o", "overlay", "p", "pgo",
+	"pkgdir", "tags", "toolexec",
+	// test flags
+	"bench", "benchtime", "blockprofile", "blockprofilerate", "count", "coverprofile", "cpu",
+	"cpuprofile", "fuzz", "fuzzminimizetime", "fuzztime", "list", "memprofile",
+	"memprofilerate", "mutexprofile", "mutexprofilefraction", "outputdir", "parallel", "run",
+	"shuffle", "skip", "timeout", "trace", "vet",
+	// list, mod edit and work edit flags
+	"f", "dropexclude", "dropgodebug", "dropreplace", "droprequire", "dropretract",
+	"droptool", "dropuse", "exclude", "go", "godebug", "module", "replace", "require",
+	"retract", "tool", "toolchain", "use",
+}
+
+// flagValue reports whether args[0] is the flag name and returns its value
+// and the number of args it consumes.
+func flagValue(args []string, name string) (value string, n int, ok bool) {
+	arg := args[0]
+	if !strings.HasPrefix(arg, "-") {
+		return "", 0, false
+	}
+	arg = strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+	if v, found := strings.CutPrefix(arg, name+"="); found {
+		return v, 1, true
+	}
+	if arg == name && len(args) > 1 {
+		return args[1], 2, true
+	}
+	return "", 0, false
 }
 
 var ErrNotFoundLocalVersion = fmt.Errorf("not found local go")
 
+// findLocalVersion returns the name of the newest installed version
+// matching v.
 func findLocalVersion(baseDir string, v *version) (string, error) {
 	files, err := os.ReadDir(filepath.Join(baseDir, "versions"))
 	if err != nil {
 		return "", err
 	}
-	var matchFiles []localFile
+	var matchNames []string
 	for _, file := range files {
-		splitName := strings.Split(strings.TrimLeft(filepath.Base(file.Name()), "go"), ".")
-		if compareVersionString(splitName, v) {
-			matchFiles = append(matchFiles, localFile{
-				name:     file.Name(),
-				priority: calcPriority(splitName),
-			})
+		if matchVersion(file.Name(), v) {
+			matchNames = append(matchNames, file.Name())
 		}
 	}
-	if len(matchFiles) == 0 {
+	if len(matchNames) == 0 {
 		return "", ErrNotFoundLocalVersion
 	}
-	slices.SortFunc(matchFiles, func(l, r localFile) int {
-		return r.priority - l.priority
-	})
-	return matchFiles[0].name, nil
+	return slices.MaxFunc(matchNames, goversion.Compare), nil
 }
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+var forwardSignals = []os.Signal{os.Interrupt}
+
+func execCommand(path string, args, env []string) error {
+	return runCommand(path, args, env)
+}
+
+func signalExitCode(*os.ProcessState) (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestToolchainEnviron(t *testing.T) {
+	versionDir := filepath.Join("/home/user", gvsDir, "versions", "go1.22.1")
+	bin := filepath.Join(versionDir, "bin")
+	toolBin := filepath.Join(versionDir, toolBinDir)
+	pathList := func(dirs ...string) string {
+		return "PATH=" + strings.Join(dirs, string(filepath.ListSeparator))
+	}
+
+	tests := []struct {
+		name    string
+		environ []string
+		want    []string
+	}{
+		{
+			name:    "stale GOROOT is dropped",
+			environ: []string{"HOME=/home/user", "GOROOT=/usr/local/go", pathList("/usr/bin")},
+			want:    []string{"HOME=/home/user", "GOROOT=" + versionDir, pathList(bin, toolBin, "/usr/bin"), "GOTOOLCHAIN=local"},
+		},
+		{
+			name:    "bin directories come first without duplicates",
+			environ: []string{pathList("/usr/bin", toolBin, "/bin", bin)},
+			want:    []string{"GOROOT=" + versionDir, pathList(bin, toolBin, "/usr/bin", "/bin"), "GOTOOLCHAIN=local"},
+		},
+		{
+			name:    "GOTOOLCHAIN is local when it is absent",
+			environ: []string{pathList("/usr/bin")},
+			want:    []string{"GOROOT=" + versionDir, pathList(bin, toolBin, "/usr/bin"), "GOTOOLCHAIN=local"},
+		},
+		{
+			name:    "GOTOOLCHAIN of the user is kept",
+			environ: []string{"GOTOOLCHAIN=auto", pathList("/usr/bin")},
+			want:    []string{"GOTOOLCHAIN=auto", "GOROOT=" + versionDir, pathList(bin, toolBin, "/usr/bin")},
+		},
+		{
+			name:    "empty GOTOOLCHAIN of the user is kept",
+			environ: []string{"GOTOOLCHAIN=", pathList("/usr/bin")},
+			want:    []string{"GOTOOLCHAIN=", "GOROOT=" + versionDir, pathList(bin, toolBin, "/usr/bin")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := toolchainEnviron(tt.environ, versionDir)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("toolchainEnviron() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPinnedEnviron(t *testing.T) {
+	versionDir := filepath.Join("/home/user", gvsDir, "versions", "go1.21.5")
+	for _, toolchain := range []string{"", "GOTOOLCHAIN=auto", "GOTOOLCHAIN=go1.22.1+auto", "GOTOOLCHAIN=local"} {
+		environ := []string{"PATH=/usr/bin"}
+		if toolchain != "" {
+			environ = append(environ, toolchain)
+		}
+		got := pinnedEnviron(environ, versionDir)
+		var toolchains []string
+		for _, kv := range got {
+			if strings.HasPrefix(kv, "GOTOOLCHAIN=") {
+				toolchains = append(toolchains, kv)
+			}
+		}
+		if !slices.Equal(toolchains, []string{"GOTOOLCHAIN=local"}) {
+			t.Errorf("pinnedEnviron(%q) has %q, want GOTOOLCHAIN=local", environ, toolchains)
+		}
+	}
+}
+
+func TestRunPrerelease(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          []string
+		localVersion string
+		want         string
+	}{
+		{
+			name: "release candidate by GVS_VERSION",
+			env:  []string{"GVS_VERSION=1.23rc1"},
+			want: "go1.23rc1",
+		},
+		{
+			name: "beta by GVS_VERSION",
+			env:  []string{"GVS_VERSION=1.23beta1"},
+			want: "go1.23beta1",
+		},
+		{
+			name:         "release candidate by .go-version",
+			localVersion: "1.23rc2",
+			want:         "go1.23rc2",
+		},
+		{
+			name: "major version prefers releases",
+			env:  []string{"GVS_VERSION=1"},
+			want: "go1.22.1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, project := setupHome(t, "go1.22.1", "go1.23beta1", "go1.23rc1", "go1.23rc2")
+			if tt.localVersion != "" {
+				writeFile(t, filepath.Join(project, localVersionFile), tt.localVersion)
+			}
+			got, err := runGVS(t, project, tt.env, "run", "go", "--", "version")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.TrimSpace(got) != tt.want {
+				t.Errorf("run go = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindLocalVersion(t *testing.T) {
+	baseDir, _ := setupHome(t, "go1.20", "go1.20.1", "go1.21rc1", "go1.21.0", "go1.21.10", "go1.21.9")
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"1.20", "go1.20.1"},
+		{"1.20.0", "go1.20"},
+		{"1.21", "go1.21.10"},
+		{"1.21rc1", "go1.21rc1"},
+		{"1", "go1.21.10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			v, err := parseVersionString(tt.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := findLocalVersion(baseDir, v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("findLocalVersion(%s) = %s, want %s", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoTarget(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		goflags     string
+		wantDir     string
+		wantModFile string
+	}{
+		{name: "no flags", args: []string{"build", "./..."}, wantDir: "."},
+		{name: "-C dir before the command", args: []string{"-C", "sub", "build"}, wantDir: "sub"},
+		{name: "-C=dir before the command", args: []string{"-C=sub", "build"}, wantDir: "sub"},
+		{name: "-C dir after the command", args: []string{"test", "-C", "sub", "./..."}, wantDir: "sub"},
+		{name: "-C=dir after the command", args: []string{"test", "-C=sub"}, wantDir: "sub"},
+		{name: "-modfile file", args: []string{"build", "-modfile", "x.mod", "."}, wantDir: ".", wantModFile: "x.mod"},
+		{name: "--modfile=file", args: []string{"build", "--modfile=x.mod"}, wantDir: ".", wantModFile: "x.mod"},
+		{name: "-modfile after a flag with a value", args: []string{"test", "-run", "TestX", "-modfile=x.mod", "."}, wantDir: ".", wantModFile: "x.mod"},
+		{name: "-modfile of a subcommand", args: []string{"mod", "tidy", "-modfile=x.mod"}, wantDir: ".", wantModFile: "x.mod"},
+		{name: "-modfile in GOFLAGS", args: []string{"build"}, goflags: "-mod=mod -modfile=x.mod", wantDir: ".", wantModFile: "x.mod"},
+		{name: "-modfile in args overrides GOFLAGS", args: []string{"build", "-modfile=y.mod"}, goflags: "-modfile=x.mod", wantDir: ".", wantModFile: "y.mod"},
+		{name: "-modfile after -args", args: []string{"test", "./...", "-args", "-modfile=x.mod"}, wantDir: "."},
+		{name: "-modfile after -args without packages", args: []string{"test", "-args", "-modfile=x.mod"}, wantDir: "."},
+		{name: "-modfile after --", args: []string{"test", "--", "-modfile=x.mod"}, wantDir: "."},
+		{name: "-modfile after the package of go run", args: []string{"run", ".", "-modfile=x.mod"}, wantDir: "."},
+		{name: "-modfile after the file of go run", args: []string{"run", "main.go", "-modfile", "x.mod"}, wantDir: "."},
+		{name: "-C after the package of go run", args: []string{"run", ".", "-C", "sub"}, wantDir: "."},
+		{name: "flags of go tool", args: []string{"tool", "pprof", "-modfile=x.mod"}, wantDir: "."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GOFLAGS", tt.goflags)
+			dir, modFile := goTarget(tt.args)
+			if dir != tt.wantDir || modFile != tt.wantModFile {
+				t.Errorf("goTarget(%q) = %q, %q, want %q, %q", tt.args, dir, modFile, tt.wantDir, tt.wantModFile)
+			}
+		})
+	}
+}
+
+func TestRunUnreadableModFile(t *testing.T) {
+	_, project := setupHome(t, "go1.21.5", "go1.22.1")
+	writeFile(t, filepath.Join(project, "go.mod"), "module example.com/p\n\ngo 1.21.5\n")
+
+	for _, args := range [][]string{
+		{"run", "go", "--", "test", "./...", "-args", "-modfile=x.mod"},
+		{"run", "go", "--", "build", "-modfile=missing.mod"},
+	} {
+		got, err := runGVS(t, project, nil, args...)
+		if err != nil {
+			t.Fatalf("%q: %v", args, err)
+		}
+		if strings.TrimSpace(got) != "go1.21.5" {
+			t.Errorf("%q runs %q, want go1.21.5", args, got)
+		}
+	}
+}
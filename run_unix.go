@@ -0,0 +1,29 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// forwardSignals are forwarded to the child, so that killing gvs also stops
+// the command it runs.
+var forwardSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP}
+
+// execCommand replaces the gvs process with the command, so that signals and
+// the exit status reach the caller directly.
+func execCommand(path string, args, env []string) error {
+	if err := syscall.Exec(path, append([]string{path}, args...), env); err != nil {
+		return &os.PathError{Op: "exec", Path: path, Err: err}
+	}
+	return nil
+}
+
+func signalExitCode(state *os.ProcessState) (int, bool) {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return 0, false
+	}
+	return 128 + int(status.Signal()), true
+}
@@ -0,0 +1,61 @@
+//go:build unix
+
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestStartAndWaitForwardsSignals(t *testing.T) {
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := `trap 'echo caught; exit 7' INT TERM QUIT HUP; echo ready; while :; do sleep 0.01; done`
+
+	for _, sig := range []syscall.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP} {
+		t.Run(sig.String(), func(t *testing.T) {
+			_, project := setupHome(t, "go1.22.1")
+			cmd := exec.Command(self, "matrix", "--versions", "1.22.1", "--", "sh", "-c", script)
+			cmd.Dir = project
+			cmd.Env = append(os.Environ(), "TEST_GVS_MAIN=1")
+			// gvs runs in its own process group, so only gvs receives the signal.
+			cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+			stdout, err := cmd.StdoutPipe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := cmd.Start(); err != nil {
+				t.Fatal(err)
+			}
+			timer := time.AfterFunc(10*time.Second, func() { syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) })
+			defer timer.Stop()
+
+			reader := bufio.NewReader(stdout)
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					t.Fatalf("the child does not start: %v", err)
+				}
+				if strings.Contains(line, "ready") {
+					break
+				}
+			}
+			if err := cmd.Process.Signal(sig); err != nil {
+				t.Fatal(err)
+			}
+			rest, _ := io.ReadAll(reader)
+			cmd.Wait()
+			if !strings.Contains(string(rest), "caught") {
+				t.Errorf("%s is not forwarded to the child: %q", sig, rest)
+			}
+		})
+	}
+}
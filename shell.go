@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	shellShellArg string
+	shellUnsetArg bool
+)
+
+var ShellCmd = &cobra.Command{
+	Use:   "shell [version]",
+	Short: "Select Go version in the current shell session",
+	Long: `Select Go version in the current shell session by GVS_VERSION.
+This requires the shell integration printed by "gvs hook".`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var versionStr string
+		if len(args) > 0 {
+			versionStr = args[0]
+		}
+		if err := Shell(cmd.Context(), shellShellArg, versionStr); err != nil {
+			fatal(cmd.Context(), err)
+		}
+	},
+}
+
+func init() {
+	ShellCmd.Flags().StringVar(&shellShellArg, "shell", "", "shell of the output(bash, zsh or fish). default is $SHELL")
+	ShellCmd.Flags().BoolVar(&shellUnsetArg, "unset", false, "unset the version of the shell session")
+}
+
+func Shell(ctx context.Context, shell, versionStr string) error {
+	if shell == "" {
+		shell = filepath.Base(os.Getenv("SHELL"))
+	}
+	if !slices.Contains(shells, shell) {
+		shell = "bash"
+	}
+
+	switch {
+	case shellUnsetArg:
+		_, err := os.Stdout.WriteString(unsetShell(shell, versionEnv))
+		return err
+	case versionStr == "":
+		v, ok := os.LookupEnv(versionEnv)
+		if !ok {
+			return fmt.Errorf("no shell-specific version configured")
+		}
+		fmt.Fprintf(os.Stdout, "echo %s;\n", quoteShell(shell, v))
+		return nil
+	}
+
+	parsedVersion, err := parseVersionString(versionStr)
+	if err != nil {
+		return err
+	}
+	baseDir, err := checkInit()
+	if err != nil {
+		return err
+	}
+	if _, err := findLocalVersion(baseDir, parsedVersion); err != nil {
+		if !errors.Is(err, ErrNotFoundLocalVersion) {
+			return err
+		}
+		warnf(ctx, "%s is not installed. It will be downloaded at the first run", versionStr)
+	}
+	_, err = os.Stdout.WriteString(exportShell(shell, versionEnv, strings.TrimLeft(versionStr, "vgo")))
+	return err
+}
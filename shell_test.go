@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShell(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "release candidate",
+			args: []string{"--shell", "bash", "1.23rc1"},
+			want: "export GVS_VERSION='1.23rc1';\n",
+		},
+		{
+			name: "beta with go prefix",
+			args: []string{"--shell", "bash", "go1.23beta1"},
+			want: "export GVS_VERSION='1.23beta1';\n",
+		},
+		{
+			name: "pre-release which is not installed",
+			args: []string{"--shell", "fish", "1.24rc2"},
+			want: "set -gx GVS_VERSION '1.24rc2';\n",
+		},
+		{
+			name:    "invalid version",
+			args:    []string{"--shell", "bash", "1.x"},
+			wantErr: "1.x is not support format",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, project := setupHome(t, "go1.22.1", "go1.23rc1", "go1.23beta1")
+			got, err := runGVS(t, project, nil, append([]string{"shell"}, tt.args...)...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
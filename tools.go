@@ -0,0 +1,395 @@
+package main
+
+import (
+	"context"
+	"debug/buildinfo"
+	"errors"
+	"fmt"
+	goversion "go/version"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+var (
+	toolsSyncCheckArg  bool
+	toolsRebuildAllArg bool
+)
+
+var ToolsCmd = &cobra.Command{
+	Use:   "tools",
+	Short: "Manage tools of the project listed in " + toolsManifestFile,
+}
+
+var ToolsSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Install missing or outdated tools listed in " + toolsManifestFile,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		ctx := cmd.Context()
+		ok, err := SyncTools(ctx, toolsSyncCheckArg)
+		exitOnError(ctx, err)
+		if !ok {
+			os.Exit(1)
+		}
+	},
+}
+
+var ToolsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List installed tools with the Go version they were built with",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		if err := ListTools(cmd.Context()); err != nil {
+			exitOnError(cmd.Context(), err)
+		}
+	},
+}
+
+var ToolsRebuildCmd = &cobra.Command{
+	Use:   "rebuild",
+	Short: "Reinstall tools built with versions older than the global version",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		if err := RebuildTools(cmd.Context(), toolsRebuildAllArg); err != nil {
+			exitOnError(cmd.Context(), err)
+		}
+	},
+}
+
+func init() {
+	ToolsSyncCmd.Flags().BoolVar(&toolsSyncCheckArg, "check", false, "only check the tools and exit with 1 if any is missing or outdated")
+	ToolsRebuildCmd.Flags().BoolVar(&toolsRebuildAllArg, "all", false, "reinstall all tools, not only stale ones")
+	ToolsCmd.AddCommand(ToolsSyncCmd)
+	ToolsCmd.AddCommand(ToolsListCmd)
+	ToolsCmd.AddCommand(ToolsRebuildCmd)
+}
+
+// toolsManifestFile lists tools as "package@version" per line.
+const toolsManifestFile = ".gvs-tools"
+
+type tool struct {
+	pkg     string
+	version string
+}
+
+func (t tool) String() string { return t.pkg + "@" + t.version }
+
+// binaryName returns the name of the binary go install creates.
+func (t tool) binaryName() string {
+	elem := path.Base(t.pkg)
+	if prefix, _, ok := module.SplitPathVersion(t.pkg); ok && prefix != t.pkg && prefix != "" {
+		elem = path.Base(prefix)
+	}
+	if runtime.GOOS == "windows" {
+		elem += ".exe"
+	}
+	return elem
+}
+
+// findToolsManifest returns the nearest manifest in dir or its parents.
+func findToolsManifest(dir string) (string, error) {
+	directory, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		manifest := filepath.Join(directory, toolsManifestFile)
+		if _, err := os.Stat(manifest); err == nil {
+			return manifest, nil
+		}
+		parent := filepath.Dir(directory)
+		if parent == directory {
+			return "", fmt.Errorf("%s is not found", toolsManifestFile)
+		}
+		directory = parent
+	}
+}
+
+func readToolsManifest(manifest string) ([]tool, error) {
+	var (
+		tools   []tool
+		lineErr error
+	)
+	err := scanFile(manifest, func(line int, text string) bool {
+		text, _, _ = strings.Cut(text, "#")
+		if text = strings.TrimSpace(text); text == "" {
+			return true
+		}
+		pkg, version, ok := strings.Cut(text, "@")
+		if !ok || pkg == "" || version == "" {
+			lineErr = fmt.Errorf("%s:%d: expected package@version", manifest, line)
+			return false
+		}
+		tools = append(tools, tool{pkg: pkg, version: version})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tools, lineErr
+}
+
+type toolState string
+
+const (
+	toolOK       toolState = "ok"
+	toolMissing  toolState = "missing"
+	toolOutdated toolState = "outdated"
+)
+
+// checkTool compares the binary in binDir with t by its build info.
+func checkTool(binDir string, t tool) (toolState, string) {
+	info, err := buildinfo.ReadFile(filepath.Join(binDir, t.binaryName()))
+	if err != nil {
+		return toolMissing, ""
+	}
+	if info.Path != t.pkg || !semver.IsValid(t.version) || info.Main.Version != t.version {
+		return toolOutdated, info.Main.Version
+	}
+	return toolOK, info.Main.Version
+}
+
+// SyncTools installs the tools of the manifest with the version of the
+// project into gvs-bin of the version. Builds are cached per version and
+// package@version in $HOME/.gvs/cache/tools. With check, it only reports
+// whether all tools are up to date.
+func SyncTools(ctx context.Context, check bool) (bool, error) {
+	baseDir, err := checkInit()
+	if err != nil {
+		return false, err
+	}
+	manifest, err := findToolsManifest(".")
+	if err != nil {
+		return false, err
+	}
+	tools, err := readToolsManifest(manifest)
+	if err != nil {
+		return false, err
+	}
+	versionDir, err := installedVersionDir(ctx, baseDir, autoVersion, filepath.Dir(manifest), "")
+	if err != nil {
+		return false, err
+	}
+	binDir := filepath.Join(versionDir, toolBinDir)
+
+	allOK := true
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "TOOL\tWANT\tINSTALLED\tSTATE\n")
+	for _, t := range tools {
+		state, installed := checkTool(binDir, t)
+		if state != toolOK && !check {
+			if err := syncTool(ctx, baseDir, versionDir, t); err != nil {
+				return false, fmt.Errorf("install %s: %w", t, err)
+			}
+			state, installed = checkTool(binDir, t)
+			if state == toolOutdated && !semver.IsValid(t.version) {
+				// a query like latest is resolved by go install.
+				state = toolOK
+			}
+		}
+		if state != toolOK {
+			allOK = false
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", t.pkg, t.version, installed, state)
+	}
+	if err := tw.Flush(); err != nil {
+		return false, err
+	}
+	if !check {
+		if err := Rehash(ctx); err != nil {
+			return false, err
+		}
+	}
+	return allOK, nil
+}
+
+func syncTool(ctx context.Context, baseDir, versionDir string, t tool) error {
+	cached := filepath.Join(toolCacheDir(baseDir, versionDir, t), t.binaryName())
+	if _, err := os.Stat(cached); err != nil || !semver.IsValid(t.version) {
+		if err := goInstall(ctx, versionDir, filepath.Dir(cached), []string{t.String()}); err != nil {
+			return err
+		}
+	} else {
+		debugf(ctx, "use cache %s", cached)
+	}
+
+	binDir := filepath.Join(versionDir, toolBinDir)
+	if err := os.MkdirAll(binDir, 0o755); err != nil {
+		return err
+	}
+	return copyFile(cached, filepath.Join(binDir, t.binaryName()))
+}
+
+func toolCacheDir(baseDir, versionDir string, t tool) string {
+	escaped, err := module.EscapePath(t.pkg)
+	if err != nil {
+		escaped = t.pkg
+	}
+	return filepath.Join(baseDir, cacheDir, "tools", filepath.Base(versionDir), filepath.FromSlash(escaped)+"@"+t.version)
+}
+
+// copyFile replaces to by a hard link to from, or a copy if it fails.
+func copyFile(from, to string) error {
+	if err := os.Remove(to); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Link(from, to); err == nil {
+		return nil
+	}
+
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return errors.Join(err, dst.Close())
+}
+
+// installedTool is a Go binary found in a tool directory.
+type installedTool struct {
+	dir  string
+	name string
+	info *buildinfo.BuildInfo
+}
+
+// stale reports whether the tool is built with a version older than global.
+func (t installedTool) stale(global string) bool {
+	return global != "" && goversion.Compare(t.info.GoVersion, global) < 0
+}
+
+// toolDirs returns gvs-bin of the global version and the GOBIN of the user.
+func toolDirs(baseDir, global string) []string {
+	var dirs []string
+	if global != "" {
+		dirs = append(dirs, filepath.Join(baseDir, "versions", global, toolBinDir))
+	}
+	if gobin := os.Getenv("GOBIN"); gobin != "" {
+		dirs = append(dirs, gobin)
+	} else if gopath := filepath.SplitList(os.Getenv("GOPATH")); len(gopath) > 0 && gopath[0] != "" {
+		dirs = append(dirs, filepath.Join(gopath[0], "bin"))
+	} else if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, "go", "bin"))
+	}
+	return dirs
+}
+
+// installedGlobalVersion returns the installed directory name of the
+// global version, or "" if it is not set or not installed.
+func installedGlobalVersion(baseDir string) string {
+	v, err := readGlobalVersion(baseDir)
+	if err != nil {
+		return ""
+	}
+	parsedVersion, err := parseVersionString(v)
+	if err != nil {
+		return ""
+	}
+	name, err := findLocalVersion(baseDir, parsedVersion)
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
+func findInstalledTools(dirs []string) ([]installedTool, error) {
+	var tools []installedTool
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			info, err := buildinfo.ReadFile(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				continue
+			}
+			tools = append(tools, installedTool{dir: dir, name: entry.Name(), info: info})
+		}
+	}
+	return tools, nil
+}
+
+func ListTools(_ context.Context) error {
+	baseDir, err := checkInit()
+	if err != nil {
+		return err
+	}
+	global := installedGlobalVersion(baseDir)
+	dirs := toolDirs(baseDir, global)
+	tools, err := findInstalledTools(dirs)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "NAME\tMODULE\tVERSION\tGO\tDIR\n")
+	stale := false
+	for _, t := range tools {
+		mark := ""
+		if t.stale(global) {
+			mark = " (stale)"
+			stale = true
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s%s\t%s\n", t.name, t.info.Main.Path, t.info.Main.Version, t.info.GoVersion, mark, t.dir)
+	}
+	if stale {
+		fmt.Fprintf(tw, "\nstale: built with a version older than the global version %s\n", global)
+	}
+	return tw.Flush()
+}
+
+// RebuildTools reinstalls stale tools (or all tools) with the global
+// version into the directory they are found in.
+func RebuildTools(ctx context.Context, all bool) error {
+	baseDir, err := checkInit()
+	if err != nil {
+		return err
+	}
+	global := installedGlobalVersion(baseDir)
+	if global == "" {
+		return ErrNotFoundGlobalVersion
+	}
+	tools, err := findInstalledTools(toolDirs(baseDir, global))
+	if err != nil {
+		return err
+	}
+	for _, t := range tools {
+		if !all && !t.stale(global) {
+			continue
+		}
+		version := t.info.Main.Version
+		if version == "" || version == "(devel)" {
+			warnf(ctx, "skip %s: it is not installed by a module version", t.name)
+			continue
+		}
+		infof(ctx, "rebuild %s@%s built with %s", t.info.Path, version, t.info.GoVersion)
+		if err := installInto(ctx, "", t.dir, []string{t.info.Path + "@" + version}); err != nil {
+			return fmt.Errorf("rebuild %s: %w", t.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	goversion "go/version"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	uninstallForceArg  bool
+	pruneKeepLatestArg bool
+	pruneOlderThanArg  string
+	pruneDryRunArg     bool
+)
+
+var UninstallCmd = &cobra.Command{
+	Use:   "uninstall [version]",
+	Short: "Remove installed versions matching the version",
+	Long: `Remove installed versions matching the version, e.g. 1.21 removes every
+installed 1.21.x. The global version is not removed without --force.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := Uninstall(cmd.Context(), args[0], uninstallForceArg); err != nil {
+			fatal(cmd.Context(), err)
+		}
+	},
+}
+
+var PruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove versions not used by the global version or known projects",
+	Long: `Remove installed versions which neither the global version nor the known
+projects resolve to. Known projects are the directories where gvs resolved a
+version and where "gvs use --local" was run.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		ctx := cmd.Context()
+		olderThan, err := parseAge(pruneOlderThanArg)
+		if err != nil {
+			fatal(ctx, err)
+		}
+		if err := Prune(ctx, pruneKeepLatestArg, olderThan, pruneDryRunArg); err != nil {
+			fatal(ctx, err)
+		}
+	},
+}
+
+func init() {
+	UninstallCmd.Flags().BoolVar(&uninstallForceArg, "force", false, "remove the global version too")
+	PruneCmd.Flags().BoolVar(&pruneKeepLatestArg, "keep-latest-patch", false, "keep the latest patch of each minor version")
+	PruneCmd.Flags().StringVar(&pruneOlderThanArg, "older-than", "", "remove only versions installed before this duration (e.g. 90d, 12h)")
+	PruneCmd.Flags().BoolVar(&pruneDryRunArg, "dry-run", false, "print versions to remove without removing")
+}
+
+// Uninstall removes the installed versions matching versionStr.
+func Uninstall(ctx context.Context, versionStr string, force bool) error {
+	baseDir, err := checkInit()
+	if err != nil {
+		return err
+	}
+	parsedVersion, err := parseVersionString(versionStr)
+	if err != nil {
+		return err
+	}
+	names, err := installedVersions(baseDir)
+	if err != nil {
+		return err
+	}
+	var targets []string
+	for _, name := range names {
+		if matchVersion(name, parsedVersion) {
+			targets = append(targets, name)
+		}
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("%s: %w", versionStr, ErrNotFoundLocalVersion)
+	}
+	if global := installedGlobalVersion(baseDir); !force && slices.Contains(targets, global) {
+		return fmt.Errorf("%s is the global version. Run with --force to remove it", global)
+	}
+
+	for _, name := range targets {
+		infof(ctx, "remove %s", name)
+		if err := removeVersion(baseDir, name); err != nil {
+			return err
+		}
+	}
+	return Rehash(ctx)
+}
+
+// Prune removes installed versions which the global version and known
+// projects do not resolve to. olderThan limits the removal to versions
+// installed before that long ago if it is not zero.
+func Prune(ctx context.Context, keepLatestPatch bool, olderThan time.Duration, dryRun bool) error {
+	baseDir, err := checkInit()
+	if err != nil {
+		return err
+	}
+	names, err := installedVersions(baseDir)
+	if err != nil {
+		return err
+	}
+	keep, err := usedVersions(ctx, baseDir)
+	if err != nil {
+		return err
+	}
+	if keepLatestPatch {
+		latest := make(map[string]string)
+		for _, name := range names {
+			minor := goversion.Lang(name)
+			if cur, ok := latest[minor]; !ok || goversion.Compare(name, cur) > 0 {
+				latest[minor] = name
+			}
+		}
+		for _, name := range latest {
+			keep[name] = true
+		}
+	}
+
+	var removed int
+	for _, name := range names {
+		if keep[name] {
+			debugf(ctx, "keep %s", name)
+			continue
+		}
+		if olderThan > 0 {
+			installed, err := installedAt(filepath.Join(baseDir, "versions", name))
+			if err != nil {
+				return err
+			}
+			if time.Since(installed) < olderThan {
+				debugf(ctx, "keep %s installed at %s", name, installed.Format(time.DateOnly))
+				continue
+			}
+		}
+		removed++
+		if dryRun {
+			fmt.Fprintf(os.Stdout, "would remove %s\n", name)
+			continue
+		}
+		infof(ctx, "remove %s", name)
+		if err := removeVersion(baseDir, name); err != nil {
+			return err
+		}
+	}
+	if removed == 0 {
+		infof(ctx, "nothing to prune")
+	}
+	if dryRun || removed == 0 {
+		return nil
+	}
+	return Rehash(ctx)
+}
+
+// usedVersions returns installed versions which the global version and known
+// projects resolve to. Known projects are the cached resolutions and the
+// directories recorded by use --local.
+func usedVersions(ctx context.Context, baseDir string) (map[string]bool, error) {
+	used := make(map[string]bool)
+	add := func(versionStr string) {
+		parsedVersion, err := parseVersionString(versionStr)
+		if err != nil {
+			return
+		}
+		if name, err := findLocalVersion(baseDir, parsedVersion); err == nil {
+			used[name] = true
+		}
+	}
+
+	if global, err := readGlobalVersion(baseDir); err == nil {
+		add(global)
+	} else if !errors.Is(err, ErrNotFoundGlobalVersion) {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(filepath.Join(baseDir, cacheDir, "resolve"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if source, ok := readResolveCache(ctx, filepath.Join(baseDir, cacheDir, "resolve", entry.Name())); ok {
+			add(source.Version)
+		}
+	}
+
+	projects, err := readProjects(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range projects {
+		if _, err := os.Stat(dir); err != nil {
+			debugf(ctx, "skip project %s: %v", dir, err)
+			continue
+		}
+		v, err := decideVersion(ctx, baseDir, dir, "")
+		if err != nil {
+			debugf(ctx, "resolve %s: %v", dir, err)
+			continue
+		}
+		add(v)
+	}
+	return used, nil
+}
+
+// installedVersions returns the installed versions in ascending order.
+func installedVersions(baseDir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(baseDir, "versions"))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if goversion.IsValid(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	slices.SortFunc(names, goversion.Compare)
+	return names, nil
+}
+
+// installedAt returns when the version was installed. It is the modification
+// time of VERSION, which extract writes without keeping the time in the
+// archive and nothing changes later, unlike the directory which changes
+// when e.g. gvs-bin is created in it.
+func installedAt(versionDir string) (time.Time, error) {
+	info, err := os.Stat(filepath.Join(versionDir, "VERSION"))
+	if os.IsNotExist(err) {
+		info, err = os.Stat(versionDir)
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// removeVersion removes the version and the caches built with it.
+func removeVersion(baseDir, name string) error {
+	for _, dir := range []string{
+		filepath.Join(baseDir, "versions", name),
+		filepath.Join(baseDir, cacheDir, "go-build", name),
+		filepath.Join(baseDir, cacheDir, "tools", name),
+	} {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("remove %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// parseAge parses a duration which also accepts days, e.g. 90d.
+func parseAge(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	goversion "go/version"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	upgradeGlobalArg bool
+	upgradeDirsArg   []string
+	upgradeCleanArg  bool
+)
+
+var UpgradeCmd = &cobra.Command{
+	Use:   "upgrade [version]",
+	Short: "Download the latest patch release of installed minor versions",
+	Long: `Download the latest patch release of each installed minor version, or of the
+minor version of the argument (e.g. 1.22). Version files pinned to an older
+patch of an upgraded minor version can be rewritten with --global and --dirs.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		var minor string
+		if len(args) > 0 {
+			minor = args[0]
+		}
+		if err := Upgrade(ctx, minor, upgradeGlobalArg, upgradeDirsArg, upgradeCleanArg); err != nil {
+			fatal(ctx, err)
+		}
+	},
+}
+
+func init() {
+	UpgradeCmd.Flags().BoolVar(&upgradeGlobalArg, "global", false, "rewrite the global version")
+	UpgradeCmd.Flags().StringSliceVar(&upgradeDirsArg, "dirs", nil, "rewrite .go-version in the directories (comma separated)")
+	UpgradeCmd.Flags().BoolVar(&upgradeCleanArg, "clean", false, "remove superseded patch releases not used by the global version or known projects")
+}
+
+// Upgrade installs the latest patch release of each installed minor version,
+// or of the minor version of minorStr if it is not empty.
+func Upgrade(ctx context.Context, minorStr string, global bool, dirs []string, clean bool) error {
+	baseDir, err := checkInit()
+	if err != nil {
+		return err
+	}
+	installed, err := installedVersions(baseDir)
+	if err != nil {
+		return err
+	}
+
+	var minors []string
+	if minorStr != "" {
+		v := "go" + strings.TrimLeft(minorStr, "vgo")
+		if !goversion.IsValid(v) {
+			return fmt.Errorf("%s is not support format", minorStr)
+		}
+		minors = append(minors, goversion.Lang(v))
+	} else {
+		for _, name := range installed {
+			if lang := goversion.Lang(name); len(minors) == 0 || minors[len(minors)-1] != lang {
+				minors = append(minors, lang)
+			}
+		}
+	}
+	if len(minors) == 0 {
+		infof(ctx, "no versions are installed")
+		return nil
+	}
+
+	versions, err := fetchRemoteVersions(ctx)
+	if err != nil {
+		return err
+	}
+	releases := make(map[string]*GoVersion)
+	for _, v := range versions {
+		releases[v.Version] = v
+	}
+	latest := make(map[string]string)
+	for _, patch := range latestPatches(versions) {
+		latest[goversion.Lang(patch)] = patch
+	}
+
+	upgraded := make(map[string]string)
+	for _, minor := range minors {
+		patch, ok := latest[minor]
+		if !ok {
+			warnf(ctx, "no stable release of %s is found", minor)
+			continue
+		}
+		upgraded[minor] = patch
+		if _, err := os.Stat(filepath.Join(baseDir, "versions", patch)); err == nil {
+			infof(ctx, "%s is up to date", patch)
+			continue
+		}
+		infof(ctx, "upgrade %s to %s", minor, patch)
+		if err := downloadGoVersion(ctx, releases[patch]); err != nil {
+			return err
+		}
+	}
+
+	var files []string
+	if global {
+		files = append(files, filepath.Join(baseDir, globalVersionFile))
+	}
+	for _, dir := range dirs {
+		files = append(files, filepath.Join(dir, localVersionFile))
+	}
+	for _, file := range files {
+		if err := rewritePin(ctx, file, upgraded); err != nil {
+			return err
+		}
+	}
+
+	if clean {
+		return cleanSuperseded(ctx, baseDir, upgraded)
+	}
+	return nil
+}
+
+// rewritePin rewrites the version file if it is pinned to an older patch
+// release of an upgraded minor version. Versions without a patch number
+// already select the latest installed patch and are left as is.
+func rewritePin(ctx context.Context, path string, upgraded map[string]string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			warnf(ctx, "%s is not found", path)
+			return nil
+		}
+		return err
+	}
+	pin := strings.TrimSpace(string(b))
+	v := "go" + strings.TrimLeft(pin, "vgo")
+	patch, ok := upgraded[goversion.Lang(v)]
+	if !ok || strings.Count(v, ".") < 2 || goversion.Compare(v, patch) >= 0 {
+		return nil
+	}
+	newPin := strings.TrimPrefix(patch, "go")
+	infof(ctx, "rewrite %s: %s -> %s", path, pin, newPin)
+	return os.WriteFile(path, []byte(strings.Replace(string(b), pin, newPin, 1)), 0644)
+}
+
+// cleanSuperseded removes installed patches older than the upgraded ones,
+// keeping versions used by the global version or known projects.
+func cleanSuperseded(ctx context.Context, baseDir string, upgraded map[string]string) error {
+	installed, err := installedVersions(baseDir)
+	if err != nil {
+		return err
+	}
+	used, err := usedVersions(ctx, baseDir)
+	if err != nil {
+		return err
+	}
+	var removed bool
+	for _, name := range installed {
+		patch, ok := upgraded[goversion.Lang(name)]
+		if !ok || goversion.Compare(name, patch) >= 0 {
+			continue
+		}
+		if used[name] {
+			warnf(ctx, "keep %s because it is still used", name)
+			continue
+		}
+		infof(ctx, "remove %s", name)
+		if err := removeVersion(baseDir, name); err != nil {
+			return err
+		}
+		removed = true
+	}
+	if !removed {
+		return nil
+	}
+	return Rehash(ctx)
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -29,6 +31,7 @@ func init() {
 var (
 	globalVersionFile = "version"
 	localVersionFile  = ".go-version"
+	projectsFile      = "projects"
 )
 
 func Use(_ context.Context, versionStr string) error {
@@ -50,5 +53,52 @@ func Use(_ context.Context, versionStr string) error {
 	if err := os.WriteFile(filepath.Join(baseDir, versionFile), []byte(strings.TrimLeft(versionStr, "vgo")), 0644); err != nil {
 		return err
 	}
+	if useLocalArg {
+		if gvsBase, err := checkInit(); err == nil {
+			return recordProject(gvsBase, baseDir)
+		}
+	}
 	return nil
 }
+
+// recordProject adds dir to the projects file, which prune reads to keep
+// versions used by projects.
+func recordProject(baseDir, dir string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	projects, err := readProjects(baseDir)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(projects, dir) {
+		return nil
+	}
+	file, err := os.OpenFile(filepath.Join(baseDir, projectsFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(file, dir); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func readProjects(baseDir string) ([]string, error) {
+	b, err := os.ReadFile(filepath.Join(baseDir, projectsFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var projects []string
+	for _, line := range strings.Split(string(b), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			projects = append(projects, line)
+		}
+	}
+	return projects, nil
+}
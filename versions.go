@@ -2,11 +2,8 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -42,24 +39,10 @@ func init() {
 var versionRegex = regexp.MustCompile(`^go[0-9]{1,2}\.[0-9]{1,2}\.[0-9]{1,2}/$`)
 
 func outputRemoteVersions(ctx context.Context) error {
-	r, err := http.NewRequestWithContext(ctx, http.MethodGet, goVersionURL, nil)
+	versions, err := fetchRemoteVersions(ctx)
 	if err != nil {
 		return err
 	}
-	resp, err := http.DefaultClient.Do(r)
-	if err != nil {
-		return err
-	}
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		return fmt.Errorf("status is %d. response %s", resp.StatusCode, body)
-	}
-
-	var versions []*GoVersion
-	if err := json.NewDecoder(resp.Body).Decode(&versions); err != nil {
-		return fmt.Errorf("decode response body: %w", err)
-	}
 	var buf strings.Builder
 	for _, version := range versions {
 		buf.WriteString(version.Version)
@@ -106,7 +89,7 @@ func outputLocalVersions(ctx context.Context) error {
 		return filepath.Base(path), nil
 	}
 
-	current, err := decideVersion(ctx, baseDir)
+	current, err := decideVersion(ctx, baseDir, ".", "")
 	if err != nil {
 		if os.IsNotExist(err) {
 			debugf(ctx, "local version is not found")